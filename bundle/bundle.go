@@ -0,0 +1,153 @@
+package bundle
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/whalebrew/whalebrew/packages"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the name of the bundle manifest looked up in the current directory
+const DefaultFileName = "Whalebrewfile"
+
+// Bundle lists the packages that must be installed
+type Bundle struct {
+	Packages []Entry `yaml:"packages"`
+}
+
+// Entry describes a package to install from an image
+type Entry struct {
+	Image      string `yaml:"image"`
+	Name       string `yaml:"name,omitempty"`
+	Entrypoint string `yaml:"entrypoint,omitempty"`
+	// Overrides replaces fields of the package generated from the image,
+	// using the same format as installed packages
+	Overrides yaml.Node `yaml:"overrides,omitempty"`
+}
+
+// State is the installation state of a package compared to the bundle
+type State string
+
+const (
+	// StateInstalled is used for packages installed as described in the bundle
+	StateInstalled State = "installed"
+	// StateMissing is used for packages of the bundle that are not installed
+	StateMissing State = "missing"
+	// StateChanged is used for installed packages that differ from the bundle
+	StateChanged State = "changed"
+	// StateExtraneous is used for installed packages that are not in the bundle
+	StateExtraneous State = "extraneous"
+)
+
+// Status describes the installation state of a package
+type Status struct {
+	Name      string
+	Image     string
+	State     State
+	Diff      string
+	Package   *packages.Package
+	Installed *packages.Package
+}
+
+// Load reads a bundle from a manifest file
+func Load(path string) (*Bundle, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	d := yaml.NewDecoder(fd)
+	d.KnownFields(true)
+	b := &Bundle{}
+	if err := d.Decode(b); err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %v", path, err)
+	}
+	for i, entry := range b.Packages {
+		if entry.Image == "" {
+			return nil, fmt.Errorf("invalid bundle %s: package at index %d has no image", path, i)
+		}
+	}
+	return b, nil
+}
+
+// Package builds the package described by the entry from the image configuration
+func (e Entry) Package(imageInspect *imagev1.Image) (*packages.Package, error) {
+	pkg, err := packages.NewPackageFromImage(e.Image, imageInspect)
+	if err != nil {
+		return nil, err
+	}
+	if e.Name != "" {
+		pkg.Name = e.Name
+	}
+	if e.Entrypoint != "" {
+		pkg.Entrypoint = []string{e.Entrypoint}
+	}
+	if !e.Overrides.IsZero() {
+		// yaml.Node.Decode does not support rejecting unknown fields
+		// re-encode the overrides to decode them strictly.
+		overrides, err := yaml.Marshal(&e.Overrides)
+		if err != nil {
+			return nil, err
+		}
+		d := yaml.NewDecoder(bytes.NewReader(overrides))
+		d.KnownFields(true)
+		if err := d.Decode(pkg); err != nil {
+			return nil, fmt.Errorf("invalid overrides for image %s: %v", e.Image, err)
+		}
+	}
+	return pkg, nil
+}
+
+// Check compares the packages of a bundle with the ones installed.
+// Packages installed but not part of the bundle are reported as extraneous.
+func Check(pm *packages.PackageManager, wanted []*packages.Package) ([]Status, error) {
+	installed, err := pm.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list packages: %v", err)
+	}
+	statuses := []Status{}
+	wantedNames := map[string]bool{}
+	for _, pkg := range wanted {
+		if wantedNames[pkg.Name] {
+			return nil, fmt.Errorf("package %s is defined more than once in the bundle", pkg.Name)
+		}
+		wantedNames[pkg.Name] = true
+		status := Status{
+			Name:    pkg.Name,
+			Image:   pkg.Image,
+			State:   StateInstalled,
+			Package: pkg,
+		}
+		if current, ok := installed[pkg.Name]; ok {
+			status.Installed = current
+			if changed, diff := current.Diff(pkg); changed {
+				status.State = StateChanged
+				status.Diff = diff
+			}
+		} else {
+			status.State = StateMissing
+		}
+		statuses = append(statuses, status)
+	}
+
+	extraneous := []Status{}
+	for name, pkg := range installed {
+		if !wantedNames[name] {
+			extraneous = append(extraneous, Status{
+				Name:      name,
+				Image:     pkg.Image,
+				State:     StateExtraneous,
+				Installed: pkg,
+			})
+		}
+	}
+	sort.Slice(extraneous, func(i, j int) bool {
+		return extraneous[i].Name < extraneous[j].Name
+	})
+	return append(statuses, extraneous...), nil
+}
@@ -0,0 +1,116 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+func writeBundle(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("with a valid bundle", func(t *testing.T) {
+		b, err := Load(writeBundle(t, `
+packages:
+- image: whalebrew/jq
+- image: whalebrew/awscli
+  name: aws
+  entrypoint: /usr/bin/aws
+  overrides:
+    environment:
+    - AWS_PROFILE
+`))
+		require.NoError(t, err)
+		require.Len(t, b.Packages, 2)
+		assert.Equal(t, "whalebrew/jq", b.Packages[0].Image)
+		assert.Equal(t, "aws", b.Packages[1].Name)
+		assert.Equal(t, "/usr/bin/aws", b.Packages[1].Entrypoint)
+	})
+	t.Run("with an unknown field", func(t *testing.T) {
+		_, err := Load(writeBundle(t, "packages:\n- image: whalebrew/jq\n  unknown: value\n"))
+		assert.Error(t, err)
+	})
+	t.Run("without image", func(t *testing.T) {
+		_, err := Load(writeBundle(t, "packages:\n- name: jq\n"))
+		assert.Error(t, err)
+	})
+	t.Run("when the file does not exist", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), DefaultFileName))
+		assert.Error(t, err)
+	})
+}
+
+func TestEntryPackage(t *testing.T) {
+	image := &imagev1.Image{
+		Config: imagev1.ImageConfig{
+			Labels: map[string]string{
+				"io.whalebrew.config.environment": `["TERM"]`,
+				"io.whalebrew.config.ports":       `["8080:8080"]`,
+			},
+		},
+	}
+	b, err := Load(writeBundle(t, `
+packages:
+- image: whalebrew/awscli
+  name: aws
+  entrypoint: /usr/bin/aws
+  overrides:
+    environment:
+    - AWS_PROFILE
+- image: whalebrew/jq
+  overrides:
+    unknown: value
+`))
+	require.NoError(t, err)
+
+	pkg, err := b.Packages[0].Package(image)
+	require.NoError(t, err)
+	assert.Equal(t, "aws", pkg.Name)
+	assert.Equal(t, []string{"/usr/bin/aws"}, pkg.Entrypoint)
+	assert.Equal(t, []string{"AWS_PROFILE"}, pkg.Environment)
+	assert.Equal(t, []string{"8080:8080"}, pkg.Ports)
+
+	_, err = b.Packages[1].Package(image)
+	assert.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
+	pm := packages.NewPackageManager(t.TempDir())
+	require.NoError(t, pm.Install(&packages.Package{Name: "jq", Image: "whalebrew/jq"}))
+	require.NoError(t, pm.Install(&packages.Package{Name: "aws", Image: "whalebrew/awscli"}))
+	require.NoError(t, pm.Install(&packages.Package{Name: "wget", Image: "whalebrew/wget"}))
+
+	statuses, err := Check(pm, []*packages.Package{
+		{Name: "jq", Image: "whalebrew/jq"},
+		{Name: "aws", Image: "whalebrew/awscli", Environment: []string{"AWS_PROFILE"}},
+		{Name: "ffmpeg", Image: "whalebrew/ffmpeg"},
+	})
+	require.NoError(t, err)
+	require.Len(t, statuses, 4)
+	assert.Equal(t, "jq", statuses[0].Name)
+	assert.Equal(t, StateInstalled, statuses[0].State)
+	assert.Equal(t, "aws", statuses[1].Name)
+	assert.Equal(t, StateChanged, statuses[1].State)
+	assert.Contains(t, statuses[1].Diff, "AWS_PROFILE")
+	assert.Equal(t, "ffmpeg", statuses[2].Name)
+	assert.Equal(t, StateMissing, statuses[2].State)
+	assert.Equal(t, "wget", statuses[3].Name)
+	assert.Equal(t, StateExtraneous, statuses[3].State)
+
+	_, err = Check(pm, []*packages.Package{
+		{Name: "jq", Image: "whalebrew/jq"},
+		{Name: "jq", Image: "other/jq"},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+
+	"github.com/Songmu/prompter"
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/bundle"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/hooks"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+var bundleFile string
+
+func init() {
+	bundleCommand.PersistentFlags().StringVar(&bundleFile, "file", bundle.DefaultFileName, "Path to the bundle manifest.")
+	bundleInstallCommand.Flags().BoolVarP(&forceInstall, "force", "f", false, "Replace installed packages that differ from the bundle. Defaults to false.")
+	bundleInstallCommand.Flags().BoolVarP(&assumeYes, "assume-yes", "y", false, "Assume 'yes' as answer to all prompts and run non-interactively. Defaults to false.")
+	bundleCleanupCommand.Flags().BoolVarP(&assumeYes, "assume-yes", "y", false, "Assume 'yes' as answer to all prompts and run non-interactively. Defaults to false.")
+
+	bundleCommand.AddCommand(bundleInstallCommand)
+	bundleCommand.AddCommand(bundleCheckCommand)
+	bundleCommand.AddCommand(bundleCleanupCommand)
+	RootCmd.AddCommand(bundleCommand)
+}
+
+// checkBundle builds the packages of the bundle and compares them with the installed ones
+func checkBundle(pm *packages.PackageManager) ([]bundle.Status, error) {
+	b, err := bundle.Load(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+	docker, err := newEngine()
+	if err != nil {
+		return nil, err
+	}
+
+	var errorList multipleErrors
+	wanted := []*packages.Package{}
+	for _, entry := range b.Packages {
+		imageInspect, err := docker.ImageInspect(entry.Image)
+		if err != nil {
+			errorList = append(errorList, ErrorWithImage{Image: entry.Image, Err: err})
+			continue
+		}
+		if err := lintForInstall(imageInspect, entry.Entrypoint); err != nil {
+			errorList = append(errorList, ErrorWithImage{Image: entry.Image, Err: err})
+			continue
+		}
+		pkg, err := entry.Package(imageInspect)
+		if err != nil {
+			errorList = append(errorList, ErrorWithImage{Image: entry.Image, Err: err})
+			continue
+		}
+		wanted = append(wanted, pkg)
+	}
+	if errorList != nil {
+		return nil, errorList
+	}
+	return bundle.Check(pm, wanted)
+}
+
+var bundleCommand = &cobra.Command{
+	Use:   "bundle",
+	Short: "Manage packages from a bundle manifest",
+	Long:  "Install, check and clean up packages according to a manifest (Whalebrewfile) listing images and their customisations.",
+}
+
+var bundleInstallCommand = &cobra.Command{
+	Use:   "install",
+	Short: "Install the packages of the bundle that are missing",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installDir := config.GetConfig().InstallPath
+		if err := ensureInstallDir(installDir); err != nil {
+			return err
+		}
+		pm := packages.NewPackageManager(installDir)
+
+		statuses, err := checkBundle(pm)
+		if err != nil {
+			return err
+		}
+
+		toInstall := []bundle.Status{}
+		for _, status := range statuses {
+			switch status.State {
+			case bundle.StateMissing:
+				toInstall = append(toInstall, status)
+			case bundle.StateChanged:
+				if !forceInstall {
+					fmt.Printf("⚠️   %s differs from the bundle, use --force to overwrite it:\n", status.Name)
+					fmt.Println(status.Diff)
+					continue
+				}
+				toInstall = append(toInstall, status)
+			}
+		}
+		if len(toInstall) == 0 {
+			fmt.Println("✅  All packages of the bundle are installed")
+			return nil
+		}
+
+		for _, status := range toInstall {
+			fmt.Printf("📦  %s (%s)\n", status.Name, status.Image)
+			if err := checkPolicy(status.Package); err != nil {
+				return err
+			}
+			if message := status.Package.PreinstallMessage(status.Installed); message != "" {
+				fmt.Println(message)
+			}
+		}
+		if !assumeYes {
+			if !prompter.YN(fmt.Sprintf("Would you like to install %d package(s)?", len(toInstall)), true) {
+				return fmt.Errorf("Not installing packages")
+			}
+		}
+
+		for _, status := range toInstall {
+			docker, err := newEngineFor(status.Package.Runtime)
+			if err != nil {
+				return err
+			}
+			if err := writePackage(pm, docker, status.Image, status.Package, status.State == bundle.StateChanged, packages.WithSource("bundle "+bundleFile)); err != nil {
+				return err
+			}
+			fmt.Printf("🐳  Installed %s to %s\n", status.Image, path.Join(pm.InstallPath, status.Name))
+			warnIfNotInPath(pm, status.Name)
+		}
+		return nil
+	},
+}
+
+var bundleCheckCommand = &cobra.Command{
+	Use:   "check",
+	Short: "Report differences between the bundle and the installed packages",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+		statuses, err := checkBundle(pm)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 10, 2, 2, ' ', 0)
+		fmt.Fprintln(w, "COMMAND\tIMAGE\tSTATE")
+		unsatisfied := 0
+		for _, status := range statuses {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", status.Name, status.Image, status.State)
+			if status.State == bundle.StateMissing || status.State == bundle.StateChanged {
+				unsatisfied++
+			}
+		}
+		w.Flush()
+
+		for _, status := range statuses {
+			if status.State == bundle.StateChanged {
+				fmt.Printf("\n%s differs from the bundle:\n%s", status.Name, status.Diff)
+			}
+		}
+		if unsatisfied > 0 {
+			return fmt.Errorf("%d package(s) do not match the bundle", unsatisfied)
+		}
+		return nil
+	},
+}
+
+var bundleCleanupCommand = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Uninstall the packages that are not in the bundle",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+		statuses, err := checkBundle(pm)
+		if err != nil {
+			return err
+		}
+
+		extraneous := []bundle.Status{}
+		for _, status := range statuses {
+			if status.State == bundle.StateExtraneous {
+				extraneous = append(extraneous, status)
+				fmt.Printf("🗑   %s (%s)\n", path.Join(pm.InstallPath, status.Name), status.Image)
+			}
+		}
+		if len(extraneous) == 0 {
+			fmt.Println("✅  No package outside of the bundle is installed")
+			return nil
+		}
+		if !assumeYes {
+			if !prompter.YN(fmt.Sprintf("This will permanently delete %d package(s). Are you sure?", len(extraneous)), false) {
+				return nil
+			}
+		}
+
+		for _, status := range extraneous {
+			payload := hooks.Payload{Package: status.Installed}
+			if err := hooks.Run("pre-uninstall", payload, status.Name); err != nil {
+				return fmt.Errorf("pre-uninstall install script failed: %s", err.Error())
+			}
+			if err := pm.Uninstall(status.Name); err != nil {
+				return err
+			}
+			unlockPackage(status.Name)
+			if err := hooks.Run("post-uninstall", payload, status.Name); err != nil {
+				return fmt.Errorf("post-uninstall install script failed: %s", err.Error())
+			}
+			fmt.Printf("🚽  Uninstalled %s\n", path.Join(pm.InstallPath, status.Name))
+		}
+		return nil
+	},
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/doctor"
+	"github.com/whalebrew/whalebrew/hooks"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+var doctorOutput string
+
+func init() {
+	addOutputFlag(doctorCommand, &doctorOutput)
+
+	RootCmd.AddCommand(doctorCommand)
+}
+
+// pingEngine creates the configured container engine to check it answers
+func pingEngine() (run.Pinger, error) {
+	engine, err := newEngine()
+	if err != nil {
+		return nil, err
+	}
+	pinger, ok := engine.(run.Pinger)
+	if !ok {
+		return nil, fmt.Errorf("the container engine can not be checked")
+	}
+	return pinger, nil
+}
+
+func doctorChecks() []doctor.Check {
+	installPath := config.GetConfig().InstallPath
+	return []doctor.Check{
+		doctor.InstallPathCheck{InstallPath: installPath, Path: os.Getenv("PATH")},
+		doctor.CommandsCheck{InstallPath: installPath},
+		doctor.PackagesCheck{InstallPath: installPath, Loader: packages.DefaultLoader},
+		doctor.ShebangsCheck{InstallPath: installPath},
+		doctor.VersionsCheck{InstallPath: installPath, Loader: packages.DefaultLoader},
+		doctor.RuntimeCheck{Engine: pingEngine},
+		doctor.HooksCheck{Dir: hooks.Dir()},
+	}
+}
+
+var doctorCommand = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check whalebrew and the installed packages are working",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(doctorOutput); err != nil {
+			return err
+		}
+		report := doctor.Run(doctorChecks()...)
+		if err := writeOutput(os.Stdout, doctorOutput, report, report.WriteText); err != nil {
+			return err
+		}
+		switch {
+		case report.OK:
+			return nil
+		case doctorOutput == "" || doctorOutput == outputTable:
+			return fmt.Errorf("some checks failed")
+		default:
+			// the failures are already reported in the requested format
+			return run.ExitError{Code: 1}
+		}
+	},
+}
@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"path"
-	"syscall"
+	"path/filepath"
 
+	"github.com/Songmu/prompter"
 	"github.com/spf13/cobra"
 	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/lint"
 	"github.com/whalebrew/whalebrew/packages"
 )
 
@@ -15,10 +19,52 @@ func init() {
 	RootCmd.AddCommand(editCommand)
 }
 
+// editPackage lets edit change a copy of the package and installs the copy once valid.
+// When the copy is invalid, retry tells whether to edit it again or to discard the changes.
+func editPackage(pm *packages.PackageManager, name string, edit func(path string) error, retry func() bool) error {
+	packagePath := path.Join(pm.InstallPath, name)
+	content, err := os.ReadFile(packagePath)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "whalebrew-edit-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	copyPath := filepath.Join(dir, name)
+	if err := os.WriteFile(copyPath, content, 0644); err != nil {
+		return err
+	}
+
+	for {
+		if err := edit(copyPath); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(copyPath)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, content) {
+			return nil
+		}
+		findings := lint.PackageContent(packagePath, edited, false)
+		if !lint.Failed(findings) {
+			return pm.Edit(name, edited)
+		}
+		for _, f := range findings {
+			fmt.Fprintf(os.Stderr, "❌  %s\n", f.Message)
+		}
+		if !retry() {
+			return fmt.Errorf("the edited package %s is invalid, changes were discarded", name)
+		}
+	}
+}
+
 var editCommand = &cobra.Command{
 	Use:   "edit PACKAGENAME",
 	Short: "Edit a package file",
-	Long:  "Edit a package file using your default editor ($EDITOR).",
+	Long:  "Edit a package file using your default editor ($EDITOR). The package is only replaced once the changes are valid.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return cmd.Help()
@@ -26,10 +72,13 @@ var editCommand = &cobra.Command{
 
 		pkgName := args[0]
 		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
-		_, err := pm.Load(pkgName)
+		isPackage, err := packages.IsPackage(path.Join(pm.InstallPath, pkgName))
 		if err != nil {
 			return err
 		}
+		if !isPackage {
+			return fmt.Errorf("%s is not a Whalebrew package", pkgName)
+		}
 
 		editor, ok := os.LookupEnv("EDITOR")
 		if !ok {
@@ -44,11 +93,19 @@ var editCommand = &cobra.Command{
 			return err
 		}
 
-		editorArgs := []string{
-			editorPath,
-			path.Join(pm.InstallPath, pkgName),
-		}
-
-		return syscall.Exec(editorPath, editorArgs, os.Environ())
+		return editPackage(
+			pm,
+			pkgName,
+			func(path string) error {
+				c := exec.Command(editorPath, path)
+				c.Stdin = os.Stdin
+				c.Stdout = os.Stdout
+				c.Stderr = os.Stderr
+				return c.Run()
+			},
+			func() bool {
+				return prompter.YN("Would you like to edit the package again?", true)
+			},
+		)
 	},
 }
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+func TestEditPackage(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
+	pm := packages.NewPackageManager(t.TempDir())
+	require.NoError(t, pm.Install(&packages.Package{Name: "jq", Image: "whalebrew/jq"}))
+	original, err := os.ReadFile(filepath.Join(pm.InstallPath, "jq"))
+	require.NoError(t, err)
+
+	writer := func(contents ...string) func(string) error {
+		return func(path string) error {
+			content := contents[0]
+			contents = contents[1:]
+			return os.WriteFile(path, []byte(content), 0644)
+		}
+	}
+	never := func() bool {
+		t.Error("the package should not be edited again")
+		return false
+	}
+
+	t.Run("without changes", func(t *testing.T) {
+		require.NoError(t, editPackage(pm, "jq", writer(string(original)), never))
+		revisions, err := pm.History.Revisions("jq")
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+	})
+
+	t.Run("with invalid changes", func(t *testing.T) {
+		retried := 0
+		assert.Error(t, editPackage(pm, "jq", writer("#!/usr/bin/env whalebrew\nimag: whalebrew/jq\n", "image: whalebrew/jq\n"), func() bool {
+			retried++
+			return retried < 2
+		}))
+		assert.Equal(t, 2, retried)
+		content, err := os.ReadFile(filepath.Join(pm.InstallPath, "jq"))
+		require.NoError(t, err)
+		assert.Equal(t, original, content)
+	})
+
+	t.Run("with valid changes", func(t *testing.T) {
+		edited := "#!/usr/bin/env whalebrew\nimage: whalebrew/jq:1.6\n"
+		require.NoError(t, editPackage(pm, "jq", writer("image: whalebrew/jq:1.6\n", edited), func() bool { return true }))
+		content, err := os.ReadFile(filepath.Join(pm.InstallPath, "jq"))
+		require.NoError(t, err)
+		assert.Equal(t, edited, string(content))
+		revisions, err := pm.History.Revisions("jq")
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "edited", revisions[0].Reason)
+		assert.Equal(t, string(original), revisions[0].Content)
+	})
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+// newEngine creates the container engine selected in the whalebrew configuration
+func newEngine() (run.Engine, error) {
+	return newEngineFor("")
+}
+
+// newEngineFor creates the container engine for a package requiring the given runtime.
+// The runtime is only relevant to the cli runner.
+func newEngineFor(runtime string) (run.Engine, error) {
+	switch config.GetConfig().Runner {
+	case "", config.RunnerCLI:
+		r := config.GetConfig().PackageRuntime(runtime)
+		return run.NewDockerLikeRunnerFor(r.Path, r.Flavour, r.Args)
+	case config.RunnerAPI:
+		return run.NewDockerAPIRunner()
+	default:
+		return nil, fmt.Errorf("unsupported runner %s, expecting %s or %s", config.GetConfig().Runner, config.RunnerCLI, config.RunnerAPI)
+	}
+}
+
+// newDigesterFor creates the engine listing the digests of local images for a package requiring the given runtime
+func newDigesterFor(runtime string) (run.ImageDigester, error) {
+	return newEngineFor(runtime)
+}
+
+// packageRunner runs executions with the engine matching the runtime they require
+type packageRunner struct{}
+
+func (packageRunner) Run(e *run.Execution) error {
+	if e == nil {
+		return fmt.Errorf("no execution provided")
+	}
+	engine, err := newEngineFor(e.Runtime)
+	if err != nil {
+		return err
+	}
+	if err := createNamedVolumes(engine, e); err != nil {
+		return err
+	}
+	return engine.Run(e)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+func init() {
+	RootCmd.AddCommand(historyCommand)
+}
+
+// revisionPackage loads a revision of a package, or the installed package when rev is empty
+func revisionPackage(pm *packages.PackageManager, name, rev string) (*packages.Package, string, error) {
+	if rev == "" {
+		if !pm.HasInstallation(name) {
+			return nil, "", fmt.Errorf("package %s is not installed in %s", name, pm.InstallPath)
+		}
+		pkg, err := pm.Load(name)
+		return pkg, "the installed package", err
+	}
+	number, err := strconv.Atoi(rev)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid revision %s, expecting a revision number", rev)
+	}
+	r, err := pm.History.Revision(name, number)
+	if err != nil {
+		return nil, "", err
+	}
+	pkg, err := r.Package()
+	return pkg, fmt.Sprintf("revision %d", r.Number), err
+}
+
+var historyCommand = &cobra.Command{
+	Use:   "history PACKAGENAME [REV [REV]]",
+	Short: "List the previous revisions of a package or compare them",
+	Long:  "List the revisions of a package kept when it was replaced or uninstalled. With one revision, show the changes from this revision to the installed package. With two revisions, show the changes between them.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 || len(args) > 3 {
+			return cmd.Help()
+		}
+		name := args[0]
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+
+		if len(args) > 1 {
+			to := ""
+			if len(args) > 2 {
+				to = args[2]
+			}
+			fromPkg, fromName, err := revisionPackage(pm, name, args[1])
+			if err != nil {
+				return err
+			}
+			toPkg, toName, err := revisionPackage(pm, name, to)
+			if err != nil {
+				return err
+			}
+			changed, diff := fromPkg.Diff(toPkg)
+			if !changed {
+				fmt.Printf("%s and %s are identical\n", fromName, toName)
+				return nil
+			}
+			fmt.Printf("Changes from %s to %s:\n%s\n", fromName, toName, diff)
+			if message := toPkg.PreinstallMessage(fromPkg); message != "" {
+				fmt.Println(message)
+			}
+			return nil
+		}
+
+		revisions, err := pm.History.Revisions(name)
+		if err != nil {
+			return err
+		}
+		if len(revisions) == 0 && !pm.HasInstallation(name) {
+			return fmt.Errorf("package %s has no history", name)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 10, 2, 2, ' ', 0)
+		fmt.Fprintln(w, "REVISION\tDATE\tIMAGE\tDIGEST\tREASON")
+		for _, r := range revisions {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.Number, r.CreatedAt.Local().Format(time.RFC3339), r.Image, shortDigest(r.Digest), r.Reason)
+		}
+		if pm.HasInstallation(name) {
+			if pkg, err := pm.Load(name); err == nil {
+				digest := ""
+				if m, err := pm.Metadata.Load(name); err == nil {
+					digest = m.Digest
+				}
+				fmt.Fprintf(w, "current\t\t%s\t%s\tinstalled\n", pkg.Image, shortDigest(digest))
+			}
+		}
+		return w.Flush()
+	},
+}
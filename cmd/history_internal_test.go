@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+func TestRevisionPackage(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
+	pm := packages.NewPackageManager(t.TempDir())
+	require.NoError(t, pm.Install(&packages.Package{Name: "jq", Image: "whalebrew/jq:1.5"}))
+	require.NoError(t, pm.ForceInstall(&packages.Package{Name: "jq", Image: "whalebrew/jq:1.6"}))
+
+	pkg, name, err := revisionPackage(pm, "jq", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "the installed package", name)
+	assert.Equal(t, "whalebrew/jq:1.6", pkg.Image)
+
+	pkg, name, err = revisionPackage(pm, "jq", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "revision 1", name)
+	assert.Equal(t, "whalebrew/jq:1.5", pkg.Image)
+
+	_, _, err = revisionPackage(pm, "jq", "2")
+	assert.Error(t, err)
+	_, _, err = revisionPackage(pm, "jq", "latest")
+	assert.Error(t, err)
+	_, _, err = revisionPackage(pm, "wget", "")
+	assert.Error(t, err)
+}
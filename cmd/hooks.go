@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/hooks"
+)
+
+var hooksOutput string
+
+func init() {
+	addOutputFlag(hooksListCommand, &hooksOutput)
+
+	hooksCommand.AddCommand(hooksListCommand)
+	RootCmd.AddCommand(hooksCommand)
+}
+
+// hookScripts lists the scripts run for each hook, in the order they run
+func hookScripts(dir string, names []string) ([]hooks.Script, error) {
+	scripts := []hooks.Script{}
+	for _, name := range names {
+		if !hooks.IsKnown(name) {
+			return nil, fmt.Errorf("%s is not a known hook, expecting one of %v", name, hooks.Known)
+		}
+		found, err := hooks.ScriptsIn(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, found...)
+	}
+	return scripts, nil
+}
+
+var hooksCommand = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage the hooks run when installing and uninstalling packages",
+}
+
+var hooksListCommand = &cobra.Command{
+	Use:   "list [HOOK...]",
+	Short: "List the scripts run for each hook",
+	Long:  "List the scripts run for each hook, in the order they run: the script named after the hook first, then the scripts of the directory named after the hook with a .d suffix.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(hooksOutput); err != nil {
+			return err
+		}
+		names := args
+		if len(names) == 0 {
+			names = hooks.Known
+		}
+		scripts, err := hookScripts(hooks.Dir(), names)
+		if err != nil {
+			return err
+		}
+		return writeOutput(os.Stdout, hooksOutput, scripts, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 10, 2, 2, ' ', 0)
+			fmt.Fprintln(tw, "HOOK\tSCRIPT")
+			for _, script := range scripts {
+				path := script.Path
+				if !script.Executable {
+					path += " (not executable)"
+				}
+				fmt.Fprintf(tw, "%s\t%s\n", script.Hook, path)
+			}
+			return tw.Flush()
+		})
+	},
+}
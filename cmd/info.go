@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/packages"
+	"gopkg.in/yaml.v3"
+)
+
+var infoOutput string
+
+// packageInfo is an installed package as printed by the info command
+type packageInfo struct {
+	Name     string             `json:"name" yaml:"name"`
+	Path     string             `json:"path" yaml:"path"`
+	Image    string             `json:"image" yaml:"image"`
+	Metadata *packages.Metadata `json:"metadata" yaml:"metadata"`
+	Package  *packages.Package  `json:"package" yaml:"package"`
+	Labels   map[string]string  `json:"labels" yaml:"labels"`
+}
+
+func init() {
+	addOutputFlag(infoCommand, &infoOutput)
+
+	RootCmd.AddCommand(infoCommand)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func (info packageInfo) writeTable(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 10, 2, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", info.Name)
+	fmt.Fprintf(w, "Path:\t%s\n", info.Path)
+	fmt.Fprintf(w, "Image:\t%s\n", info.Image)
+	if metadata := info.Metadata; metadata != nil {
+		fmt.Fprintf(w, "Digest:\t%s\n", metadata.Digest)
+		fmt.Fprintf(w, "Installed at:\t%s\n", formatTime(metadata.InstalledAt))
+		fmt.Fprintf(w, "Updated at:\t%s\n", formatTime(metadata.UpdatedAt))
+		fmt.Fprintf(w, "Source:\t%s\n", metadata.Source)
+		fmt.Fprintf(w, "Flags:\t%s\n", strings.Join(metadata.Flags, " "))
+		fmt.Fprintf(w, "Installer version:\t%s\n", metadata.InstallerVersion)
+	} else {
+		fmt.Fprintln(w, "Metadata:\tnot recorded, the package was installed by a previous version of whalebrew")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	d, err := yaml.Marshal(info.Package)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nPackage configuration:\n%s", d)
+
+	labels := []string{}
+	for label := range info.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	fmt.Fprintln(out, "\nImage labels:")
+	w = tabwriter.NewWriter(out, 10, 2, 2, ' ', 0)
+	for _, label := range labels {
+		fmt.Fprintf(w, "  %s\t%s\n", label, info.Labels[label])
+	}
+	return w.Flush()
+}
+
+var infoCommand = &cobra.Command{
+	Use:   "info PACKAGENAME",
+	Short: "Show how an installed package was installed and how it runs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return cmd.Help()
+		}
+		if err := validateOutputFormat(infoOutput); err != nil {
+			return err
+		}
+		name := args[0]
+
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+		if !pm.HasInstallation(name) {
+			return fmt.Errorf("package %s is not installed in %s", name, pm.InstallPath)
+		}
+		pkg, err := pm.Load(name)
+		if err != nil {
+			return err
+		}
+		info := packageInfo{
+			Name:    pkg.Name,
+			Path:    path.Join(pm.InstallPath, pkg.Name),
+			Image:   pkg.Image,
+			Package: pkg,
+		}
+		metadata, err := pm.Metadata.Load(name)
+		switch {
+		case err == nil:
+			info.Metadata = metadata
+		case !os.IsNotExist(err):
+			return err
+		}
+
+		docker, err := newEngineFor(pkg.Runtime)
+		if err != nil {
+			return err
+		}
+		imageInspect, err := docker.ImageInspect(pkg.Image)
+		if err != nil {
+			return err
+		}
+		info.Labels = imageInspect.Config.Labels
+
+		return writeOutput(os.Stdout, infoOutput, info, info.writeTable)
+	},
+}
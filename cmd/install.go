@@ -16,16 +16,22 @@ import (
 
 	"github.com/Songmu/prompter"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
 	"github.com/whalebrew/whalebrew/hooks"
 	"github.com/whalebrew/whalebrew/packages"
 	"github.com/whalebrew/whalebrew/run"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 var customPackageName string
 var customEntrypoint string
+var customRuntime string
 var forceInstall bool
 var assumeYes bool
+var reviewInstall bool
 var strict bool
 
 type multipleErrors []error
@@ -41,13 +47,173 @@ func (e multipleErrors) Error() string {
 func init() {
 	installCommand.Flags().StringVarP(&customPackageName, "name", "n", "", "Name to give installed package. Defaults to image name.")
 	installCommand.Flags().StringVarP(&customEntrypoint, "entrypoint", "e", "", "Custom entrypoint to run the image with. Defaults to image entrypoint.")
+	installCommand.Flags().StringVar(&customRuntime, "runtime", "", "Docker like command line to run the package with. Defaults to the configured runtime.")
 	installCommand.Flags().BoolVarP(&forceInstall, "force", "f", false, "Replace existing package if already exists. Defaults to false.")
+	installCommand.Flags().BoolVar(&reviewInstall, "review", false, "Review the package permissions reading the image configuration from its registry, before pulling the image. Defaults to false.")
 	installCommand.Flags().BoolVarP(&assumeYes, "assume-yes", "y", false, "Assume 'yes' as answer to all prompts and run non-interactively. Defaults to false.")
 	installCommand.Flags().BoolVar(&strict, "strict", false, "Fail installing the image if it contains any skippable error. Defaults to false.")
 
 	RootCmd.AddCommand(installCommand)
 }
 
+// lintForInstall returns the lint errors preventing the image to be installed.
+// When a custom entrypoint is provided, images without entrypoint can be installed.
+func lintForInstall(imageInspect *imagev1.Image, customEntrypoint string) error {
+	var errorList multipleErrors
+	packages.LintImage(imageInspect, func(e error) {
+		switch e.(type) {
+		case packages.NoEntrypointError:
+			// Exception is done for entrypoint, install offers the ability to customise its value
+			if customEntrypoint != "" {
+				return
+			}
+		}
+		if s, ok := e.(packages.StrictError); strict == true || !ok || s.Strict() {
+			errorList = append(errorList, e)
+		}
+	})
+	if errorList != nil {
+		return errorList
+	}
+	return nil
+}
+
+// ensureInstallDir creates the installation directory when it does not exist yet,
+// falling back to sudo when the current user is not allowed to create it
+func ensureInstallDir(installDir string) error {
+	_, err := os.Stat(installDir)
+	if err != nil && os.IsNotExist(err) {
+		err := os.MkdirAll(installDir, 0755)
+		if err != nil {
+			fmt.Println("ℹ️   Install directory", installDir, "is missing and requires elevated privileges to be created. Creating it with sudo")
+			c := exec.Command("sudo", "mkdir", "-m", "0755", "-p", installDir)
+			c.Stdout = os.Stdout
+			c.Stderr = os.Stderr
+			err = c.Run()
+			if err != nil {
+				return fmt.Errorf("failed to create non-existing installation directory: %v", err)
+			}
+			currentUser, err := user.Current()
+			if err != nil {
+				return fmt.Errorf("failed to change ownership of install directory to current user: %v", err)
+			}
+
+			c = exec.Command("sudo", "chown", "-R", currentUser.Username+":"+currentUser.Gid, strings.TrimSuffix(installDir, "/bin"))
+			c.Stdout = os.Stdout
+			c.Stderr = os.Stderr
+			err = c.Run()
+			if err != nil {
+				return fmt.Errorf("failed to create non-existing installation directory: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// warnIfNotInPath warns when an installed command is not the one found in PATH
+func warnIfNotInPath(pm *packages.PackageManager, name string) {
+	installPath := filepath.Clean(path.Join(pm.InstallPath, name))
+	cmdPath, err := exec.LookPath(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❗️  Installed command %s does not seem to be available after install. Ensure you add %v to your $PATH to be able to use it\n", name, pm.InstallPath)
+	} else if cmdPath != installPath {
+		fmt.Fprintf(os.Stderr, "❗️  Installed command %s does not point to installed path %s but to %s. Ensure %v is in the relevant poistion of your $PATH to be able to use it\n", name, installPath, cmdPath, pm.InstallPath)
+	}
+}
+
+// pinnedImage returns the image pinned to the digest its tag points to in its registry
+func pinnedImage(imageName string) (string, error) {
+	ref, err := dockerregistry.ParseReference(imageName)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return imageName, nil
+	}
+	digest, err := registryFor(ref).ManifestDigest(ref.Path, ref.Tag)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve the digest of %s: %w", imageName, err)
+	}
+	return imageName + "@" + digest, nil
+}
+
+// checkReviewedImage fails when the local image of imageName is not reviewedImage, the image pinned to the reviewed digest
+func checkReviewedImage(digester run.ImageDigester, imageName, reviewedImage string) error {
+	reviewed, err := dockerregistry.ParseReference(reviewedImage)
+	if err != nil {
+		return err
+	}
+	digest, err := imageDigest(digester, imageName)
+	if err != nil {
+		return err
+	}
+	if digest != reviewed.Digest {
+		return fmt.Errorf("%s changed since it was reviewed: it is now %s instead of %s", imageName, digest, reviewed.Digest)
+	}
+	return nil
+}
+
+// writePackage installs pkg in pm, running the install hooks around it,
+// recording how it was installed and locking the package to the digest of its image
+func writePackage(pm *packages.PackageManager, digester run.ImageDigester, imageName string, pkg *packages.Package, force bool, opts ...packages.InstallOption) error {
+	// when the image was not pulled yet, the image its tag points to is verified
+	digest, digestErr := imageDigest(digester, pkg.Image)
+	verified, err := verifyImage(pkg.Image, digest, false)
+	if err != nil {
+		return err
+	}
+	if verified != "" {
+		fmt.Printf("🔏  Verified the signature of %s@%s\n", pkg.Image, verified)
+		if digestErr != nil {
+			// lock the package to the verified image
+			digest, digestErr = verified, nil
+		}
+	}
+
+	var previous *packages.Package
+	if pm.HasInstallation(pkg.Name) {
+		// a package that can not be loaded is replaced without being described to hooks
+		previous, _ = pm.Load(pkg.Name)
+	}
+	payload := hooks.InstallPayload(pkg, previous)
+	if err := hooks.Run("pre-install", payload, imageName, pkg.Name); err != nil {
+		return fmt.Errorf("pre install script failed: %s", err.Error())
+	}
+
+	if digestErr == nil {
+		opts = append(opts, packages.WithDigest(digest))
+	}
+
+	if force {
+		err = pm.ForceInstall(pkg, opts...)
+	} else {
+		err = pm.Install(pkg, opts...)
+	}
+	if err != nil {
+		var patherr *fs.PathError
+		if errors.As(err, &patherr) {
+			return fmt.Errorf("Installation path is not writable: %s\n\nSet WHALEBREW_INSTALL_PATH environment variable to writable location.\nOr set 'install_path` option in '~/.whalebrew/config.yaml`. Make sure\nthe location is added to PATH. For details, see\nhttps://github.com/whalebrew/whalebrew#configuration\n", pm.InstallPath)
+		}
+		return err
+	}
+
+	lockPackage(pkg, digest, digestErr)
+
+	if err := hooks.Run("post-install", payload, pkg.Name); err != nil {
+		return fmt.Errorf("post install script failed: %s", err.Error())
+	}
+	return nil
+}
+
+// changedFlags lists the flags explicitly set on the command line
+func changedFlags(cmd *cobra.Command) []string {
+	flags := []string{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flags = append(flags, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return flags
+}
+
 var installCommand = &cobra.Command{
 	Use:   "install IMAGENAME",
 	Short: "Install a package",
@@ -62,31 +228,29 @@ var installCommand = &cobra.Command{
 
 		imageName := args[0]
 
-		docker, err := run.NewDockerLikeRunner()
+		docker, err := newEngineFor(customRuntime)
 		if err != nil {
 			return err
 		}
+		var inspecter run.ImageInspecter = docker
+		// reviewedImage is the image whose configuration is reviewed, pinned to its digest
+		// so that the image pulled afterwards can be checked to be the reviewed one even when its tag moves
+		reviewedImage := imageName
+		if reviewInstall {
+			inspecter = remoteInspecter()
+			reviewedImage, err = pinnedImage(imageName)
+			if err != nil {
+				return err
+			}
+		}
 
-		imageInspect, err := docker.ImageInspect(imageName)
+		imageInspect, err := inspecter.ImageInspect(reviewedImage)
 		if err != nil {
 			return err
 		}
 
-		var errorList multipleErrors
-		packages.LintImage(imageInspect, func(e error) {
-			switch e.(type) {
-			case packages.NoEntrypointError:
-				// Exception is done for entrypoint, install offers the ability to customise its value
-				if customEntrypoint != "" {
-					return
-				}
-			}
-			if s, ok := e.(packages.StrictError); strict == true || !ok || s.Strict() {
-				errorList = append(errorList, e)
-			}
-		})
-		if errorList != nil {
-			return errorList
+		if err := lintForInstall(imageInspect, customEntrypoint); err != nil {
+			return err
 		}
 
 		pkg, err := packages.NewPackageFromImage(imageName, imageInspect)
@@ -101,6 +265,10 @@ var installCommand = &cobra.Command{
 			pkg.Entrypoint = []string{customEntrypoint}
 		}
 
+		if customRuntime != "" {
+			pkg.Runtime = customRuntime
+		}
+
 		installDir := config.GetConfig().InstallPath
 		// we have introduced a breaking change when releasing whalebrew 0.5.0
 		// Possibly, previous installations on darwin arm64 were using /usr/local/bin.
@@ -122,31 +290,12 @@ var installCommand = &cobra.Command{
 		}
 		pm := packages.NewPackageManager(installDir)
 
-		_, err = os.Stat(installDir)
-		if err != nil && os.IsNotExist(err) {
-			err := os.MkdirAll(installDir, 0755)
-			if err != nil {
-				fmt.Println("ℹ️   Install directory", installDir, "is missing and requires elevated privileges to be created. Creating it with sudo")
-				c := exec.Command("sudo", "mkdir", "-m", "0755", "-p", installDir)
-				c.Stdout = os.Stdout
-				c.Stderr = os.Stderr
-				err = c.Run()
-				if err != nil {
-					return fmt.Errorf("failed to create non-existing installation directory: %v", err)
-				}
-				currentUser, err := user.Current()
-				if err != nil {
-					return fmt.Errorf("failed to change ownership of install directory to current user: %v", err)
-				}
+		if err := ensureInstallDir(installDir); err != nil {
+			return err
+		}
 
-				c = exec.Command("sudo", "chown", "-R", currentUser.Username+":"+currentUser.Gid, strings.TrimSuffix(installDir, "/bin"))
-				c.Stdout = os.Stdout
-				c.Stderr = os.Stderr
-				err = c.Run()
-				if err != nil {
-					return fmt.Errorf("failed to create non-existing installation directory: %v", err)
-				}
-			}
+		if err := checkPolicy(pkg); err != nil {
+			return err
 		}
 
 		var installed *packages.Package
@@ -160,7 +309,7 @@ var installCommand = &cobra.Command{
 			fmt.Printf("Looks like you already have %s installed as %s.\n", installed.Image, path.Join(installDir, pkg.Name))
 
 			if !assumeYes {
-				if changed, diff, err := installed.HasChanges(ctx, docker); err != nil {
+				if changed, diff, err := installed.HasChanges(ctx, inspecter); err != nil {
 					return err
 				} else if changed {
 					fmt.Println("There are differences between the installed version of the package and the image:")
@@ -191,25 +340,18 @@ var installCommand = &cobra.Command{
 			}
 		}
 
-		if err := hooks.Run("pre-install", imageName, pkg.Name); err != nil {
-			return fmt.Errorf("pre install script failed: %s", err.Error())
-		}
-
-		if forceInstall {
-			err = pm.ForceInstall(pkg)
-		} else {
-			err = pm.Install(pkg)
-		}
-		if err != nil {
-			var patherr *fs.PathError
-			if errors.As(err, &patherr) {
-				return fmt.Errorf("Installation path is not writable: %s\n\nSet WHALEBREW_INSTALL_PATH environment variable to writable location.\nOr set 'install_path` option in '~/.whalebrew/config.yaml`. Make sure\nthe location is added to PATH. For details, see\nhttps://github.com/whalebrew/whalebrew#configuration\n", installDir)
+		if reviewInstall {
+			// the tag is pulled, as the package runs it, and must still point to the reviewed digest
+			if err := docker.ImagePull(imageName); err != nil {
+				return err
+			}
+			if err := checkReviewedImage(docker, imageName, reviewedImage); err != nil {
+				return err
 			}
-			return err
 		}
 
-		if err := hooks.Run("post-install", pkg.Name); err != nil {
-			return fmt.Errorf("post install script failed: %s", err.Error())
+		if err := writePackage(pm, docker, imageName, pkg, forceInstall, packages.WithSource("install"), packages.WithFlags(changedFlags(cmd)...)); err != nil {
+			return err
 		}
 
 		installPath := filepath.Clean(path.Join(pm.InstallPath, pkg.Name))
@@ -219,12 +361,7 @@ var installCommand = &cobra.Command{
 			fmt.Printf("🐳  Installed %s to %s\n", imageName, installPath)
 		}
 
-		cmdPath, err := exec.LookPath(pkg.Name)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "❗️  Installed command %s does not seem to be available after install. Ensure you add %v to your $PATH to be able to use it\n", pkg.Name, pm.InstallPath)
-		} else if cmdPath != installPath {
-			fmt.Fprintf(os.Stderr, "❗️  Installed command %s does not point to installed path %s but to %s. Ensure %v is in the relevant poistion of your $PATH to be able to use it\n", pkg.Name, installPath, cmdPath, pm.InstallPath)
-		}
+		warnIfNotInPath(pm, pkg.Name)
 		return nil
 	},
 }
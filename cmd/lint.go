@@ -2,13 +2,33 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/lint"
 	"github.com/whalebrew/whalebrew/run"
 )
 
+const (
+	outputSARIF = "sarif"
+	outputJUnit = "junit"
+)
+
+var (
+	lintOutput       string
+	lintRemote       bool
+	lintDockerfiles  []string
+	lintPackageFiles []string
+)
+
 func init() {
+	lintCommand.Flags().BoolVar(&strict, "strict", false, "Fail on skippable errors like unknown labels. Defaults to false.")
+	lintCommand.Flags().BoolVar(&lintRemote, "remote", false, "Read the configuration of images from their registry instead of pulling them.")
+	lintCommand.Flags().StringArrayVar(&lintDockerfiles, "dockerfile", nil, "Dockerfile to lint the labels and entrypoint of, without building it. Can be repeated.")
+	lintCommand.Flags().StringArrayVar(&lintPackageFiles, "package-file", nil, "Package file to lint, like an installed package. Can be repeated.")
+	addOutputFlag(lintCommand, &lintOutput, outputSARIF, outputJUnit)
+
 	RootCmd.AddCommand(lintCommand)
 }
 
@@ -21,33 +41,78 @@ func (e ErrorWithImage) Error() string {
 	return fmt.Sprintf("with image %s: %v", e.Image, e.Err)
 }
 
+// writeLintReport writes the findings in the formats supported by the lint command
+func writeLintReport(w io.Writer, format string, imageNames []string, findings []lint.Finding) error {
+	switch format {
+	case outputSARIF:
+		return lint.WriteSARIF(w, findings)
+	case outputJUnit:
+		return lint.WriteJUnit(w, imageNames, findings)
+	default:
+		return writeOutput(w, format, findings, func(io.Writer) error { return nil })
+	}
+}
+
 var lintCommand = &cobra.Command{
-	Use:   "lint IMAGENAME",
+	Use:   "lint [IMAGENAME...]",
 	Short: "lints a package",
+	Long:  "Lints package images, Dockerfiles building packages or package files.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
+		if len(args) < 1 && len(lintDockerfiles) == 0 && len(lintPackageFiles) == 0 {
 			return cmd.Help()
 		}
-		docker, err := run.NewDockerLikeRunner()
-		if err != nil {
+		if err := validateOutputFormat(lintOutput, outputSARIF, outputJUnit); err != nil {
 			return err
 		}
-
-		var errors multipleErrors
-		for _, imageName := range args {
-			imageInspect, err := docker.ImageInspect(imageName)
+		findings := []lint.Finding{}
+		if len(args) > 0 {
+			var inspecter run.ImageInspecter = remoteInspecter()
+			if !lintRemote {
+				docker, err := newEngine()
+				if err != nil {
+					return err
+				}
+				inspecter = docker
+			}
+			var err error
+			findings, err = lint.Images(inspecter, args, strict)
 			if err != nil {
-				errors = append(errors, ErrorWithImage{Image: imageName, Err: err})
 				return err
 			}
-			packages.LintImage(imageInspect, func(e error) {
-				if s, ok := e.(packages.StrictError); strict == true || !ok || s.Strict() {
-					errors = append(errors, ErrorWithImage{Image: imageName, Err: e})
+		}
+		for _, dockerfile := range lintDockerfiles {
+			f, err := lint.Dockerfile(dockerfile, strict)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, f...)
+		}
+		for _, packageFile := range lintPackageFiles {
+			f, err := lint.PackageFile(packageFile, strict)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, f...)
+		}
+		linted := append(append(append([]string{}, args...), lintDockerfiles...), lintPackageFiles...)
+		if lintOutput == "" || lintOutput == outputTable {
+			var errors multipleErrors
+			for _, f := range findings {
+				if f.Severity == lint.SeverityError {
+					errors = append(errors, ErrorWithImage{Image: f.Image, Err: f.Err()})
 				}
-			})
+			}
+			if errors != nil {
+				return errors
+			}
+			return nil
+		}
+		if err := writeLintReport(os.Stdout, lintOutput, linted, findings); err != nil {
+			return err
 		}
-		if errors != nil {
-			return errors
+		if lint.Failed(findings) {
+			// the findings are already reported in the requested format
+			return run.ExitError{Code: 1}
 		}
 		return nil
 	},
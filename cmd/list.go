@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"sort"
 	"text/tabwriter"
 
@@ -11,10 +14,43 @@ import (
 	"github.com/whalebrew/whalebrew/packages"
 )
 
-var hideHeaders bool
+var (
+	hideHeaders bool
+	listOutput  string
+)
+
+// listedPackage is an installed package as printed by the list command
+type listedPackage struct {
+	Name              string `json:"name" yaml:"name"`
+	Path              string `json:"path" yaml:"path"`
+	*packages.Package `yaml:",inline"`
+}
+
+// MarshalJSON lists the name and path of the package before its configuration.
+// Without it, the JSON encoding of the embedded package would hide the name and path.
+func (p listedPackage) MarshalJSON() ([]byte, error) {
+	location, err := json.Marshal(struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}{p.Name, p.Path})
+	if err != nil || p.Package == nil {
+		return location, err
+	}
+	pkg := *p.Package
+	pkg.Name = ""
+	configuration, err := json.Marshal(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if string(configuration) == "{}" {
+		return location, nil
+	}
+	return append(append(location[:len(location)-1], ','), configuration[1:]...), nil
+}
 
 func init() {
 	listCommand.Flags().BoolVarP(&hideHeaders, "no-headers", "", false, "Hide column headers for output. Defaults to false.")
+	addOutputFlag(listCommand, &listOutput)
 
 	RootCmd.AddCommand(listCommand)
 }
@@ -23,6 +59,9 @@ var listCommand = &cobra.Command{
 	Use:   "list",
 	Short: "List installed packages",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(listOutput); err != nil {
+			return err
+		}
 		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
 		packages, err := pm.List()
 		if err != nil {
@@ -35,14 +74,20 @@ var listCommand = &cobra.Command{
 		}
 		sort.Strings(packageNames)
 
-		w := tabwriter.NewWriter(os.Stdout, 10, 2, 2, ' ', 0)
-		if !hideHeaders {
-			fmt.Fprintln(w, "COMMAND\tIMAGE")
-		}
+		listed := make([]listedPackage, 0, len(packageNames))
 		for _, name := range packageNames {
-			fmt.Fprintf(w, "%s\t%s\n", name, packages[name].Image)
+			listed = append(listed, listedPackage{Name: name, Path: filepath.Join(pm.InstallPath, name), Package: packages[name]})
 		}
-		w.Flush()
-		return nil
+
+		return writeOutput(os.Stdout, listOutput, listed, func(out io.Writer) error {
+			w := tabwriter.NewWriter(out, 10, 2, 2, ' ', 0)
+			if !hideHeaders {
+				fmt.Fprintln(w, "COMMAND\tIMAGE")
+			}
+			for _, pkg := range listed {
+				fmt.Fprintf(w, "%s\t%s\n", pkg.Name, pkg.Image)
+			}
+			return w.Flush()
+		})
 	},
 }
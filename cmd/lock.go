@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+	"github.com/whalebrew/whalebrew/lock"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+var lockPull bool
+
+func init() {
+	lockUpdateCommand.Flags().BoolVar(&lockPull, "pull", false, "Pull the images before locking their digests. Defaults to false.")
+
+	lockCommand.AddCommand(lockUpdateCommand)
+	RootCmd.AddCommand(lockCommand)
+}
+
+// imageDigest returns the registry digest of the local image of a package
+func imageDigest(digester run.ImageDigester, image string) (string, error) {
+	ref, err := dockerregistry.ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	repoDigests, err := digester.ImageRepoDigests(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		// the image is pinned, other digests of its repository may be available locally
+		return ref.Digest, nil
+	}
+	digest, ok := dockerregistry.MatchingDigest(ref, repoDigests)
+	if !ok {
+		return "", fmt.Errorf("image %s was not pulled from a registry", image)
+	}
+	return digest, nil
+}
+
+// lockPackage records the digest of the image of an installed package in the lock file.
+// When the digest could not be found, the package is removed from the lock file.
+func lockPackage(pkg *packages.Package, digest string, digestErr error) {
+	f, err := lock.Load(lock.Path())
+	if err == nil {
+		if digestErr != nil {
+			fmt.Fprintf(os.Stderr, "❗️  Unable to lock %s to the digest of %s: %v\n", pkg.Name, pkg.Image, digestErr)
+			f.Remove(pkg.Name)
+		} else {
+			f.Set(pkg.Name, pkg.Image, digest)
+		}
+		err = f.Save()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❗️  Unable to update lock file %s: %v\n", lock.Path(), err)
+	}
+}
+
+// unlockPackage removes an uninstalled package from the lock file
+func unlockPackage(name string) {
+	f, err := lock.Load(lock.Path())
+	if err == nil {
+		if _, ok := f.Get(name); !ok {
+			return
+		}
+		f.Remove(name)
+		err = f.Save()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❗️  Unable to update lock file %s: %v\n", lock.Path(), err)
+	}
+}
+
+// resolveImage returns the image to run for a package.
+// When whalebrew is locked, the image is pinned to the digest from the lock file.
+func resolveImage(pkg *packages.Package) (string, error) {
+	if !config.GetConfig().Locked {
+		return pkg.Image, nil
+	}
+	f, err := lock.Load(lock.Path())
+	if err != nil {
+		return "", err
+	}
+	entry, ok := f.Get(pkg.Name)
+	if !ok || entry.Image != pkg.Image {
+		return "", fmt.Errorf("package %s is not locked to image %s. Run 'whalebrew lock update %s' to lock it", pkg.Name, pkg.Image, pkg.Name)
+	}
+	return entry.PinnedImage()
+}
+
+var lockCommand = &cobra.Command{
+	Use:   "lock",
+	Short: "Manage the lock file pinning packages to image digests",
+	Long:  "Manage the lock file pinning packages to image digests. When locked mode is enabled, packages run the image digest from the lock file instead of their tag.",
+}
+
+var lockUpdateCommand = &cobra.Command{
+	Use:   "update [PACKAGENAME...]",
+	Short: "Lock installed packages to the digest of their local image",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+		installed, err := pm.List()
+		if err != nil {
+			return fmt.Errorf("unable to list packages: %v", err)
+		}
+		f, err := lock.Load(lock.Path())
+		if err != nil {
+			return err
+		}
+
+		names := args
+		if len(names) == 0 {
+			names = make([]string, 0, len(installed))
+			for name := range installed {
+				names = append(names, name)
+			}
+			// Forget packages that were uninstalled without whalebrew
+			for name := range f.Packages {
+				if _, ok := installed[name]; !ok {
+					f.Remove(name)
+				}
+			}
+		}
+		sort.Strings(names)
+
+		var errorList multipleErrors
+		for _, name := range names {
+			pkg, ok := installed[name]
+			if !ok {
+				return fmt.Errorf("package %s is not installed in %s", name, pm.InstallPath)
+			}
+			docker, err := newEngineFor(pkg.Runtime)
+			if err != nil {
+				errorList = append(errorList, err)
+				continue
+			}
+			if lockPull {
+				if err := docker.ImagePull(pkg.Image); err != nil {
+					errorList = append(errorList, err)
+					continue
+				}
+			}
+			digest, err := imageDigest(docker, pkg.Image)
+			if err != nil {
+				errorList = append(errorList, ErrorWithImage{Image: pkg.Image, Err: err})
+				continue
+			}
+			f.Set(name, pkg.Image, digest)
+			fmt.Printf("🔒  Locked %s to %s@%s\n", name, pkg.Image, digest)
+		}
+		if err := f.Save(); err != nil {
+			return err
+		}
+		if errorList != nil {
+			return errorList
+		}
+		return nil
+	},
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/lock"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+func TestResolveImage(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
+	t.Cleanup(config.Reset)
+	f, err := lock.Load(lock.Path())
+	require.NoError(t, err)
+	f.Set("jq", "whalebrew/jq", "sha256:1234")
+	require.NoError(t, f.Save())
+
+	t.Run("when whalebrew is not locked", func(t *testing.T) {
+		t.Setenv("WHALEBREW_LOCKED", "false")
+		config.Reset()
+		image, err := resolveImage(&packages.Package{Name: "jq", Image: "whalebrew/jq"})
+		assert.NoError(t, err)
+		assert.Equal(t, "whalebrew/jq", image)
+	})
+
+	t.Run("when whalebrew is locked", func(t *testing.T) {
+		t.Setenv("WHALEBREW_LOCKED", "true")
+		config.Reset()
+		image, err := resolveImage(&packages.Package{Name: "jq", Image: "whalebrew/jq"})
+		assert.NoError(t, err)
+		assert.Equal(t, "docker.io/whalebrew/jq@sha256:1234", image)
+
+		_, err = resolveImage(&packages.Package{Name: "jq", Image: "whalebrew/jq:1.6"})
+		assert.Error(t, err)
+		_, err = resolveImage(&packages.Package{Name: "aws", Image: "whalebrew/awscli"})
+		assert.Error(t, err)
+	})
+}
+
+func TestImageDigest(t *testing.T) {
+	digest, err := imageDigest(testDigester(func(string) ([]string, error) {
+		return []string{"whalebrew/jq@sha256:1234"}, nil
+	}), "whalebrew/jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:1234", digest)
+
+	_, err = imageDigest(testDigester(func(string) ([]string, error) {
+		return []string{}, nil
+	}), "whalebrew/jq")
+	assert.Error(t, err)
+
+	digest, err = imageDigest(testDigester(func(string) ([]string, error) {
+		return []string{"whalebrew/jq@sha256:1234", "whalebrew/jq@sha256:5678"}, nil
+	}), "whalebrew/jq@sha256:5678")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:5678", digest, "pinned images keep their digest")
+}
+
+func TestPinnedImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v2/whalebrew/jq/manifests/1.6" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", "sha256:1234")
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	dir := t.TempDir()
+	t.Setenv("WHALEBREW_CONFIG_DIR", dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("registries:\n- dockerRegistry:\n    host: "+host+"\n    useHTTP: true\n"), 0644))
+	config.Reset()
+	t.Cleanup(config.Reset)
+
+	image, err := pinnedImage(host + "/whalebrew/jq:1.6")
+	assert.NoError(t, err)
+	assert.Equal(t, host+"/whalebrew/jq:1.6@sha256:1234", image)
+	image, err = pinnedImage(host + "/whalebrew/jq@sha256:5678")
+	assert.NoError(t, err)
+	assert.Equal(t, host+"/whalebrew/jq@sha256:5678", image)
+	_, err = pinnedImage(host + "/whalebrew/jq:1.5")
+	assert.Error(t, err)
+}
+
+func TestCheckReviewedImage(t *testing.T) {
+	digester := testDigester(func(string) ([]string, error) {
+		return []string{"whalebrew/jq@sha256:1234"}, nil
+	})
+	assert.NoError(t, checkReviewedImage(digester, "whalebrew/jq:1.6", "whalebrew/jq:1.6@sha256:1234"))
+	assert.Error(t, checkReviewedImage(digester, "whalebrew/jq:1.6", "whalebrew/jq:1.6@sha256:5678"), "a tag moved since the review must not be installed")
+	assert.Error(t, checkReviewedImage(testDigester(func(string) ([]string, error) {
+		return nil, nil
+	}), "whalebrew/jq:1.6", "whalebrew/jq:1.6@sha256:1234"))
+}
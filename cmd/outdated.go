@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+var outdatedOutput string
+
+func init() {
+	addOutputFlag(outdatedCommand, &outdatedOutput)
+	outdatedCommand.Flags().BoolVarP(&hideHeaders, "no-headers", "", false, "Hide column headers for output. Defaults to false.")
+
+	RootCmd.AddCommand(outdatedCommand)
+}
+
+type outdatedStatus string
+
+const (
+	outdatedStatusUpToDate  outdatedStatus = "up to date"
+	outdatedStatusOutdated  outdatedStatus = "outdated"
+	outdatedStatusNotPulled outdatedStatus = "not pulled"
+	outdatedStatusUnknown   outdatedStatus = "unknown"
+)
+
+type outdatedPackage struct {
+	Name         string         `json:"name" yaml:"name"`
+	Image        string         `json:"image" yaml:"image"`
+	LocalDigest  string         `json:"local_digest,omitempty" yaml:"local_digest,omitempty"`
+	RemoteDigest string         `json:"remote_digest,omitempty" yaml:"remote_digest,omitempty"`
+	Status       outdatedStatus `json:"status" yaml:"status"`
+	Error        string         `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func checkOutdated(digester run.ImageDigester, pkg *packages.Package) outdatedPackage {
+	result := outdatedPackage{
+		Name:   pkg.Name,
+		Image:  pkg.Image,
+		Status: outdatedStatusUnknown,
+	}
+	ref, err := dockerregistry.ParseReference(pkg.Image)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	remoteDigest, err := registryFor(ref).ManifestDigest(ref.Path, ref.Reference())
+	if err != nil {
+		result.Error = fmt.Sprintf("unable to resolve remote digest: %v", err)
+		return result
+	}
+	result.RemoteDigest = remoteDigest
+
+	repoDigests, err := digester.ImageRepoDigests(pkg.Image)
+	if err != nil {
+		result.Status = outdatedStatusNotPulled
+		return result
+	}
+	localDigest, ok := dockerregistry.MatchingDigest(ref, repoDigests)
+	if !ok {
+		// Images built locally have no digest from the registry
+		result.Error = "local image was not pulled from the registry"
+		return result
+	}
+	result.LocalDigest = localDigest
+	if localDigest == remoteDigest {
+		result.Status = outdatedStatusUpToDate
+	} else {
+		result.Status = outdatedStatusOutdated
+	}
+	return result
+}
+
+func shortDigest(digest string) string {
+	_, hex, found := strings.Cut(digest, ":")
+	if !found {
+		hex = digest
+	}
+	if len(hex) > 12 {
+		return hex[:12]
+	}
+	return hex
+}
+
+var outdatedCommand = &cobra.Command{
+	Use:   "outdated",
+	Short: "List installed packages with a newer image in their registry",
+	Long:  "Compare the digest of the local image of each installed package with the digest in its registry, without pulling the images.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(outdatedOutput); err != nil {
+			return err
+		}
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+		packages, err := pm.List()
+		if err != nil {
+			return err
+		}
+
+		packageNames := make([]string, 0, len(packages))
+		for k := range packages {
+			packageNames = append(packageNames, k)
+		}
+		sort.Strings(packageNames)
+
+		results := make([]outdatedPackage, 0, len(packageNames))
+		for _, name := range packageNames {
+			docker, err := newEngineFor(packages[name].Runtime)
+			if err != nil {
+				return err
+			}
+			results = append(results, checkOutdated(docker, packages[name]))
+		}
+
+		return writeOutput(os.Stdout, outdatedOutput, results, func(out io.Writer) error {
+			w := tabwriter.NewWriter(out, 10, 2, 2, ' ', 0)
+			if !hideHeaders {
+				fmt.Fprintln(w, "COMMAND\tIMAGE\tLOCAL\tREMOTE\tSTATUS")
+			}
+			for _, result := range results {
+				status := string(result.Status)
+				if result.Error != "" {
+					status = fmt.Sprintf("%s (%s)", status, result.Error)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.Name, result.Image, shortDigest(result.LocalDigest), shortDigest(result.RemoteDigest), status)
+			}
+			return w.Flush()
+		})
+	},
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+type testDigester func(imageName string) ([]string, error)
+
+func (td testDigester) ImageRepoDigests(imageName string) ([]string, error) {
+	return td(imageName)
+}
+
+// useTestRegistry starts a registry serving the given digest for every manifest
+// and configures whalebrew to reach it over HTTP
+func useTestRegistry(t *testing.T, digest string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v2/some/image/manifests/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("registries:\n- dockerRegistry:\n    host: "+host+"\n    useHTTP: true\n"), 0644))
+	t.Setenv("WHALEBREW_CONFIG_DIR", dir)
+	config.Reset()
+	t.Cleanup(config.Reset)
+	return host
+}
+
+func TestCheckOutdated(t *testing.T) {
+	host := useTestRegistry(t, "sha256:1234")
+	pkg := &packages.Package{Name: "image", Image: host + "/some/image"}
+
+	t.Run("when the local image matches the registry", func(t *testing.T) {
+		result := checkOutdated(testDigester(func(string) ([]string, error) {
+			return []string{host + "/some/image@sha256:1234"}, nil
+		}), pkg)
+		assert.Equal(t, outdatedStatusUpToDate, result.Status)
+		assert.Equal(t, "sha256:1234", result.LocalDigest)
+		assert.Equal(t, "sha256:1234", result.RemoteDigest)
+	})
+
+	t.Run("when the registry has a newer image", func(t *testing.T) {
+		result := checkOutdated(testDigester(func(string) ([]string, error) {
+			return []string{host + "/some/image@sha256:5678"}, nil
+		}), pkg)
+		assert.Equal(t, outdatedStatusOutdated, result.Status)
+	})
+
+	t.Run("when the image was not pulled", func(t *testing.T) {
+		result := checkOutdated(testDigester(func(string) ([]string, error) {
+			return nil, errors.New("no such image")
+		}), pkg)
+		assert.Equal(t, outdatedStatusNotPulled, result.Status)
+	})
+
+	t.Run("when the image does not exist in the registry", func(t *testing.T) {
+		result := checkOutdated(testDigester(func(string) ([]string, error) {
+			return nil, nil
+		}), &packages.Package{Name: "other", Image: host + "/other/image"})
+		assert.Equal(t, outdatedStatusUnknown, result.Status)
+		assert.NotEmpty(t, result.Error)
+	})
+}
+
+func TestShortDigest(t *testing.T) {
+	assert.Equal(t, "0123456789ab", shortDigest("sha256:0123456789abcdef"))
+	assert.Equal(t, "1234", shortDigest("sha256:1234"))
+	assert.Equal(t, "", shortDigest(""))
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable      = "table"
+	outputJSON       = "json"
+	outputYAML       = "yaml"
+	outputGoTemplate = "go-template="
+)
+
+// addOutputFlag registers the --output flag selecting the format commands print their results with.
+// extra lists the formats the command supports besides the common ones.
+func addOutputFlag(cmd *cobra.Command, format *string, extra ...string) {
+	formats := strings.Join(append([]string{outputTable, outputJSON, outputYAML}, extra...), ", ")
+	cmd.Flags().StringVarP(format, "output", "o", outputTable, "Output format, one of "+formats+" or go-template=TEMPLATE.")
+}
+
+// validateOutputFormat reports unsupported output formats before doing any work.
+// extra lists the formats the command supports besides the common ones.
+func validateOutputFormat(format string, extra ...string) error {
+	for _, supported := range extra {
+		if format == supported {
+			return nil
+		}
+	}
+	switch {
+	case format == "", format == outputTable, format == outputJSON, format == outputYAML:
+		return nil
+	case strings.HasPrefix(format, outputGoTemplate):
+		_, err := template.New("output").Parse(strings.TrimPrefix(format, outputGoTemplate))
+		if err != nil {
+			return fmt.Errorf("invalid output template: %w", err)
+		}
+		return nil
+	default:
+		formats := strings.Join(append([]string{outputTable, outputJSON, outputYAML}, extra...), ", ")
+		return fmt.Errorf("unsupported output format %s, expecting one of %s or %sTEMPLATE", format, formats, outputGoTemplate)
+	}
+}
+
+// writeOutput writes v to w in the given format.
+// The table format is written by table, the human readable output of the command.
+func writeOutput(w io.Writer, format string, v interface{}, table func(io.Writer) error) error {
+	if err := validateOutputFormat(format); err != nil {
+		return err
+	}
+	switch {
+	case format == outputJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	case format == outputYAML:
+		e := yaml.NewEncoder(w)
+		e.SetIndent(2)
+		if err := e.Encode(v); err != nil {
+			return err
+		}
+		return e.Close()
+	case strings.HasPrefix(format, outputGoTemplate):
+		t := template.Must(template.New("output").Parse(strings.TrimPrefix(format, outputGoTemplate)))
+		return t.Execute(w, v)
+	default:
+		return table(w)
+	}
+}
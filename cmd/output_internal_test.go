@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/lint"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+func TestWriteOutput(t *testing.T) {
+	listed := []listedPackage{{
+		Name:    "jq",
+		Path:    "/usr/local/bin/jq",
+		Package: &packages.Package{Name: "jq", Image: "whalebrew/jq", Volumes: []string{"/tmp:/tmp"}},
+	}}
+	table := func(w io.Writer) error {
+		_, err := w.Write([]byte("table\n"))
+		return err
+	}
+	for format, expected := range map[string]string{
+		"":      "table\n",
+		"table": "table\n",
+		"json":  "[\n  {\n    \"name\": \"jq\",\n    \"path\": \"/usr/local/bin/jq\",\n    \"image\": \"whalebrew/jq\",\n    \"volumes\": [\n      \"/tmp:/tmp\"\n    ]\n  }\n]\n",
+		"yaml":  "- name: jq\n  path: /usr/local/bin/jq\n  image: whalebrew/jq\n  volumes:\n    - /tmp:/tmp\n",
+		"go-template={{range .}}{{.Name}} {{.Image}}{{end}}": "jq whalebrew/jq",
+	} {
+		t.Run(format, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			require.NoError(t, writeOutput(b, format, listed, table))
+			assert.Equal(t, expected, b.String())
+		})
+	}
+	assert.Error(t, writeOutput(&bytes.Buffer{}, "xml", listed, table))
+	assert.Error(t, writeOutput(&bytes.Buffer{}, "go-template={{", listed, table))
+	assert.Error(t, validateOutputFormat("go-template={{.Name"))
+	assert.Error(t, validateOutputFormat(outputSARIF))
+	assert.NoError(t, validateOutputFormat(outputSARIF, outputSARIF, outputJUnit))
+	assert.EqualError(t, validateOutputFormat("xml", outputSARIF, outputJUnit), "unsupported output format xml, expecting one of table, json, yaml, sarif, junit or go-template=TEMPLATE")
+}
+
+func TestWriteLintReport(t *testing.T) {
+	inspecter := fakeInspecter{
+		"whalebrew/jq": {Config: imagev1.ImageConfig{
+			Labels: map[string]string{
+				"io.whalebrew.config.ports":   "not a list",
+				"io.whalebrew.config.unknown": "true",
+			},
+		}},
+	}
+	findings, err := lint.Images(inspecter, []string{"whalebrew/jq"}, false)
+	require.NoError(t, err)
+	require.Len(t, findings, 3)
+	byRule := map[string]lint.Finding{}
+	for _, f := range findings {
+		assert.Equal(t, "whalebrew/jq", f.Image)
+		byRule[f.RuleID] = f
+	}
+	assert.Equal(t, lint.SeverityError, byRule[lint.RuleNoEntrypoint].Severity)
+	assert.Equal(t, lint.SeverityError, byRule[lint.RuleDecodeFailure].Severity)
+	assert.Equal(t, "io.whalebrew.config.ports", byRule[lint.RuleDecodeFailure].Label)
+	assert.Equal(t, lint.SeverityWarning, byRule[lint.RuleUnknownLabel].Severity)
+	assert.Equal(t, "io.whalebrew.config.unknown", byRule[lint.RuleUnknownLabel].Label)
+
+	for _, format := range []string{outputJSON, outputYAML, outputSARIF, outputJUnit} {
+		b := &bytes.Buffer{}
+		require.NoError(t, writeLintReport(b, format, []string{"whalebrew/jq"}, findings), format)
+		assert.Contains(t, b.String(), "io.whalebrew.config.unknown", format)
+	}
+}
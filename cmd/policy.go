@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/policy"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+var policyOutput string
+
+func init() {
+	addOutputFlag(policyCheckCommand, &policyOutput)
+
+	policyCommand.AddCommand(policyCheckCommand)
+	RootCmd.AddCommand(policyCommand)
+}
+
+// auditedPackage is an installed package as audited by the policy check command
+type auditedPackage struct {
+	Name      string            `json:"name" yaml:"name"`
+	Image     string            `json:"image" yaml:"image"`
+	Denied    bool              `json:"denied" yaml:"denied"`
+	Decisions []policy.Decision `json:"decisions" yaml:"decisions"`
+}
+
+// checkPolicy reports the decisions of the whalebrew policy about pkg
+// and fails when the policy denies it
+func checkPolicy(pkg *packages.Package) error {
+	return enforcePolicy(os.Stderr, pkg, nil, true)
+}
+
+// checkRunPolicy fails when the whalebrew policy denies running pkg as e, only reporting the denials:
+// the warnings were reported when the package was installed
+func checkRunPolicy(pkg *packages.Package, e *run.Execution) error {
+	return enforcePolicy(os.Stderr, pkg, e, false)
+}
+
+// enforcePolicy reports the decisions of the whalebrew policy about pkg to w, the warnings only withWarnings,
+// and fails when the policy denies it. When e is not nil, the volumes of this execution of pkg are evaluated.
+func enforcePolicy(w io.Writer, pkg *packages.Package, e *run.Execution, withWarnings bool) error {
+	p, err := policy.LoadDefault()
+	if err != nil {
+		return err
+	}
+	decisions := p.Evaluate(pkg)
+	if e != nil {
+		decisions = p.EvaluateExecution(pkg, e)
+	}
+	reported := []policy.Decision{}
+	for _, d := range decisions {
+		if withWarnings || d.Action == policy.ActionDeny {
+			reported = append(reported, d)
+		}
+	}
+	if report := policy.Report(reported); report != "" {
+		fmt.Fprint(w, report)
+	}
+	if policy.Denied(decisions) {
+		return fmt.Errorf("package %s is denied by the whalebrew policy", pkg.Name)
+	}
+	return nil
+}
+
+// auditPackages evaluates the installed packages against p, all of them when names is empty
+func auditPackages(p *policy.Policy, pm *packages.PackageManager, names []string) ([]auditedPackage, error) {
+	if len(names) == 0 {
+		installed, err := pm.List()
+		if err != nil {
+			return nil, err
+		}
+		for name := range installed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	audited := []auditedPackage{}
+	for _, name := range names {
+		pkg, err := pm.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		decisions := p.Evaluate(pkg)
+		audited = append(audited, auditedPackage{Name: name, Image: pkg.Image, Denied: policy.Denied(decisions), Decisions: decisions})
+	}
+	return audited, nil
+}
+
+func writeAudit(w io.Writer, audited []auditedPackage) error {
+	tw := tabwriter.NewWriter(w, 10, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tDECISION\tDESCRIPTION")
+	for _, a := range audited {
+		if len(a.Decisions) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t\n", a.Name, policy.ActionAllow)
+		}
+		for _, d := range a.Decisions {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Name, d.Action, d.Describe())
+		}
+	}
+	return tw.Flush()
+}
+
+var policyCommand = &cobra.Command{
+	Use:   "policy",
+	Short: "Manage the policy restricting the permissions of packages",
+	Long:  "The whalebrew policy allows, warns about or denies the permissions packages are given. It is read from " + policy.SystemPath + " and from " + policy.FileName + " in the whalebrew configuration directory, and enforced when installing and running packages.",
+}
+
+var policyCheckCommand = &cobra.Command{
+	Use:   "check [PACKAGENAME...]",
+	Short: "Audit installed packages against the whalebrew policy",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(policyOutput); err != nil {
+			return err
+		}
+		p, err := policy.LoadDefault()
+		if err != nil {
+			return err
+		}
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+		audited, err := auditPackages(p, pm, args)
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(os.Stdout, policyOutput, audited, func(w io.Writer) error {
+			return writeAudit(w, audited)
+		}); err != nil {
+			return err
+		}
+		denied := []string{}
+		for _, a := range audited {
+			if a.Denied {
+				denied = append(denied, a.Name)
+			}
+		}
+		switch {
+		case len(denied) == 0:
+			return nil
+		case policyOutput == "" || policyOutput == outputTable:
+			return fmt.Errorf("the whalebrew policy denies %v", denied)
+		default:
+			// the denials are already reported in the requested format
+			return run.ExitError{Code: 1}
+		}
+	},
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/policy"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+type runnerFunc func(e *run.Execution) error
+
+func (f runnerFunc) Run(e *run.Execution) error {
+	return f(e)
+}
+
+func TestPolicy(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHALEBREW_CONFIG_DIR", dir)
+	config.Reset()
+	defer config.Reset()
+	systemPath := policy.SystemPath
+	policy.SystemPath = filepath.Join(t.TempDir(), "missing.yaml")
+	defer func() { policy.SystemPath = systemPath }()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, policy.FileName), []byte("rules:\n- action: deny\n  networks: [host]\n"), 0644))
+
+	jq := &packages.Package{Name: "jq", Image: "whalebrew/jq"}
+	nmap := &packages.Package{Name: "nmap", Image: "whalebrew/nmap", Networks: []string{"host"}}
+	assert.NoError(t, checkPolicy(jq))
+	assert.EqualError(t, checkPolicy(nmap), "package nmap is denied by the whalebrew policy")
+	assert.Error(t, runPackage(runnerFunc(func(e *run.Execution) error {
+		t.Fatal("a denied package must not run")
+		return nil
+	}), nmap, nmap.Image, nil))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, policy.FileName), []byte("rules:\n- action: deny\n  networks: [host]\n- action: warn\n  ports: ['*']\n"), 0644))
+	server := &packages.Package{Name: "server", Image: "whalebrew/server", Ports: []string{"8080:80"}, Networks: []string{"host"}}
+	out := &bytes.Buffer{}
+	assert.NoError(t, enforcePolicy(out, &packages.Package{Name: "server", Image: "whalebrew/server", Ports: []string{"8080:80"}}, nil, false))
+	assert.Empty(t, out.String(), "warnings are not reported when running packages")
+	assert.Error(t, enforcePolicy(out, server, nil, false))
+	assert.NotContains(t, out.String(), "warns")
+	assert.Contains(t, out.String(), "denies")
+	out.Reset()
+	assert.Error(t, enforcePolicy(out, server, nil, true))
+	assert.Contains(t, out.String(), "warns")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, policy.FileName), []byte("rules:\n- action: deny\n  networks: [host]\n"), 0644))
+
+	pm := packages.NewPackageManager(t.TempDir())
+	require.NoError(t, pm.Install(jq))
+	require.NoError(t, pm.Install(nmap))
+	p, err := policy.LoadDefault()
+	require.NoError(t, err)
+	audited, err := auditPackages(p, pm, nil)
+	require.NoError(t, err)
+	require.Len(t, audited, 2)
+	assert.Equal(t, "jq", audited[0].Name)
+	assert.False(t, audited[0].Denied)
+	assert.Equal(t, "nmap", audited[1].Name)
+	assert.True(t, audited[1].Denied)
+
+	out.Reset()
+	require.NoError(t, writeAudit(out, audited))
+	assert.Equal(t, "PACKAGE   DECISION  DESCRIPTION\n"+
+		"jq        allow     \n"+
+		"nmap      deny      Use the network host (rule #1 of "+filepath.Join(dir, policy.FileName)+")\n", out.String())
+
+	_, err = auditPackages(p, pm, []string{"wget"})
+	assert.Error(t, err)
+}
+
+func TestRunPolicy(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHALEBREW_CONFIG_DIR", dir)
+	config.Reset()
+	defer config.Reset()
+	systemPath := policy.SystemPath
+	policy.SystemPath = filepath.Join(t.TempDir(), "missing.yaml")
+	defer func() { policy.SystemPath = systemPath }()
+	secrets := filepath.Join(t.TempDir(), "secrets")
+	require.NoError(t, os.Mkdir(secrets, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, policy.FileName), []byte("rules:\n- action: deny\n  volumes: ["+secrets+"/key]\n- action: deny\n  environment: ['*_TOKEN']\n"), 0644))
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	defer os.Chdir(cwd)
+
+	denied := runnerFunc(func(e *run.Execution) error {
+		t.Error("a denied execution must not run")
+		return nil
+	})
+	cat := &packages.Package{Name: "cat", Image: "whalebrew/cat", PathArguments: []string{"file"}}
+	assert.Error(t, runPackage(denied, cat, cat.Image, []string{"--file", filepath.Join(secrets, "key")}), "paths given as arguments are evaluated")
+	require.NoError(t, os.Chdir(secrets))
+	assert.Error(t, runPackage(denied, cat, cat.Image, nil), "the working directory is evaluated")
+	require.NoError(t, os.Chdir(t.TempDir()))
+	gh := &packages.Package{Name: "gh", Image: "whalebrew/gh", Environment: []string{"GITHUB=$GH_TOKEN"}}
+	assert.Error(t, runPackage(denied, gh, gh.Image, nil), "variables referenced in values are evaluated")
+
+	executed := false
+	assert.NoError(t, runPackage(runnerFunc(func(e *run.Execution) error {
+		executed = true
+		return nil
+	}), cat, cat.Image, []string{"--file", "other"}))
+	assert.True(t, executed)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+)
+
+// registryFor returns the registry hosting the image.
+// The whalebrew configuration tells whether the registry must be reached over plain HTTP.
+func registryFor(ref dockerregistry.Reference) *dockerregistry.Registry {
+	if ref.IsDockerHub() {
+		return &dockerregistry.Registry{}
+	}
+	r := &dockerregistry.Registry{Host: ref.Domain}
+	for _, registry := range config.GetConfig().Registries {
+		if registry.DockerRegistry != nil && registry.DockerRegistry.Host == ref.Domain {
+			r.UseHTTP = registry.DockerRegistry.UseHTTP
+		}
+	}
+	return r
+}
+
+// remoteInspecter inspects images from their registry, without pulling them
+func remoteInspecter() *dockerregistry.Inspecter {
+	return &dockerregistry.Inspecter{
+		RegistryFor: registryFor,
+		Platform:    dockerregistry.CurrentPlatform(),
+	}
+}
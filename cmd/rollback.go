@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/Songmu/prompter"
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/hooks"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+func init() {
+	rollbackCommand.Flags().BoolVarP(&assumeYes, "assume-yes", "y", false, "Assume 'yes' as answer to all prompts and run non-interactively. Defaults to false.")
+
+	RootCmd.AddCommand(rollbackCommand)
+}
+
+var rollbackCommand = &cobra.Command{
+	Use:   "rollback PACKAGENAME [REV]",
+	Short: "Restore a previous revision of a package",
+	Long:  "Restore a revision of a package listed by 'whalebrew history', the latest one when no revision is provided.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 || len(args) > 2 {
+			return cmd.Help()
+		}
+		name := args[0]
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+
+		var r *packages.Revision
+		var err error
+		if len(args) > 1 {
+			number, convErr := strconv.Atoi(args[1])
+			if convErr != nil || number < 1 {
+				return fmt.Errorf("invalid revision %s, expecting a revision number", args[1])
+			}
+			r, err = pm.History.Revision(name, number)
+		} else {
+			r, err = pm.History.Latest(name)
+		}
+		if err != nil {
+			return err
+		}
+		pkg, err := r.Package()
+		if err != nil {
+			return err
+		}
+
+		var installed *packages.Package
+		if pm.HasInstallation(name) {
+			installed, err = pm.Load(name)
+			if err != nil {
+				return err
+			}
+			if changed, diff := installed.Diff(pkg); changed {
+				fmt.Printf("Rolling back %s to revision %d changes:\n%s\n", name, r.Number, diff)
+			}
+		}
+		if err := checkPolicy(pkg); err != nil {
+			return err
+		}
+		if _, err := verifyImage(r.Image, r.Digest, false); err != nil {
+			return err
+		}
+		if message := pkg.PreinstallMessage(installed); message != "" {
+			fmt.Println(message)
+		}
+		if !assumeYes {
+			if !prompter.YN(fmt.Sprintf("Would you like to restore revision %d of %s (%s)?", r.Number, name, r.Image), true) {
+				return fmt.Errorf("Not rolling back package")
+			}
+		}
+
+		payload := hooks.InstallPayload(pkg, installed)
+		if err := hooks.Run("pre-install", payload, r.Image, name); err != nil {
+			return fmt.Errorf("pre install script failed: %s", err.Error())
+		}
+		if _, err := pm.Rollback(name, r.Number); err != nil {
+			return err
+		}
+		var digestErr error
+		if r.Digest == "" {
+			digestErr = errors.New("no digest was recorded for this revision")
+		}
+		lockPackage(pkg, r.Digest, digestErr)
+		if err := hooks.Run("post-install", payload, name); err != nil {
+			return fmt.Errorf("post install script failed: %s", err.Error())
+		}
+		fmt.Printf("⏪  Restored revision %d of %s to %s\n", r.Number, r.Image, path.Join(pm.InstallPath, name))
+		return nil
+	},
+}
@@ -1,27 +1,30 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/whalebrew/whalebrew/hooks"
 	"github.com/whalebrew/whalebrew/packages"
 	"github.com/whalebrew/whalebrew/run"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
-func shouldBind(hostPath string, pkg *packages.Package) (bool, error) {
+func shouldBind(volume packages.Volume, pkg *packages.Package) (bool, error) {
 	if pkg.MountMissingVolumes {
 		return true, nil
 	}
 	// according to docker docs, binded volumes must be provided by absolute path
 	// momn abs path are handled as docker volume names
 	// https://docs.docker.com/engine/reference/commandline/run/#mount-volume--v---read-only
-	if filepath.IsAbs(hostPath) {
-		_, err := os.Stat(hostPath)
+	if volume.IsBind() && filepath.IsAbs(volume.Host) {
+		_, err := os.Stat(volume.Host)
 		if err != nil && os.IsNotExist(err) {
 			if pkg.SkipMissingVolumes {
 				return false, nil
@@ -37,8 +40,12 @@ func getVolumes(pkg *packages.Package) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	workingDir := pkg.WorkingDir
+	if workingDir == "" {
+		workingDir = packages.DefaultWorkingDir
+	}
 	volumes := []string{}
-	for _, volume := range append(pkg.Volumes, fmt.Sprintf("%s:%s", cwd, pkg.WorkingDir)) {
+	for _, volume := range append(pkg.Volumes, fmt.Sprintf("%s:%s", cwd, workingDir)) {
 		// special case expanding home directory
 		if strings.HasPrefix(volume, "~/") {
 			user, err := user.Current()
@@ -48,7 +55,11 @@ func getVolumes(pkg *packages.Package) ([]string, error) {
 			volume = user.HomeDir + volume[1:]
 		}
 		volume = os.ExpandEnv(volume)
-		b, err := shouldBind(strings.Split(volume, ":")[0], pkg)
+		v, err := packages.ParseVolume(volume)
+		if err != nil {
+			return nil, err
+		}
+		b, err := shouldBind(v, pkg)
 		if err != nil {
 			return nil, err
 		}
@@ -59,6 +70,21 @@ func getVolumes(pkg *packages.Package) ([]string, error) {
 	return volumes, nil
 }
 
+// createNamedVolumes creates the named volumes of the execution that do not exist yet,
+// allowing packages to keep caches between runs
+func createNamedVolumes(creator run.VolumeCreator, e *run.Execution) error {
+	for _, volume := range e.Volumes {
+		v, err := packages.ParseVolume(volume)
+		if err != nil || !v.IsNamed() {
+			continue
+		}
+		if err := creator.VolumeCreate(v.Host, map[string]string{"io.whalebrew.image": e.Image}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func parseRuntimeVolumes(args []string, pkg *packages.Package) []string {
 	volumes := []string{}
 	if pkg == nil || pkg.PathArguments == nil {
@@ -105,21 +131,33 @@ func expandEnvVars(vars []string) []string {
 
 // DockerCLIRun runs the package using docker CLI forwarding the command line arguments
 func DockerCLIRun(args []string) error {
-	docker, err := run.NewDockerLikeRunner()
-	if err != nil {
-		return err
-	}
-	return Run(packages.DefaultLoader, docker, args)
+	return Run(packages.DefaultLoader, packageRunner{}, newDigesterFor, args)
 }
 
-// Run runs a package after extracting arguments
-func Run(loader packages.Loader, runner run.Runner, args []string) error {
+// Run runs a package after extracting arguments.
+// digesterFor provides the digests of local images for the runtime the package requires, to verify its image.
+func Run(loader packages.Loader, runner run.Runner, digesterFor func(runtime string) (run.ImageDigester, error), args []string) error {
 	pkg, err := loader.LoadPackageFromPath(args[1])
 	if err != nil {
 		return err
 	}
-	args = args[2:]
+	image, err := resolveImage(pkg)
+	if err != nil {
+		return err
+	}
+	digester, err := digesterFor(pkg.Runtime)
+	if err != nil {
+		return err
+	}
+	image, err = verifyLocalImage(digester, image, true)
+	if err != nil {
+		return err
+	}
+	return runPackage(runner, pkg, image, args[2:])
+}
 
+// runPackage runs the image of a package with the given command line arguments
+func runPackage(runner run.Runner, pkg *packages.Package, image string, args []string) error {
 	user, err := user.Current()
 	if err != nil {
 		return err
@@ -128,8 +166,8 @@ func Run(loader packages.Loader, runner run.Runner, args []string) error {
 	if err != nil {
 		return err
 	}
-	return runner.Run(&run.Execution{
-		Image:             pkg.Image,
+	e := &run.Execution{
+		Image:             image,
 		Entrypoint:        pkg.Entrypoint,
 		Ports:             pkg.Ports,
 		Networks:          pkg.Networks,
@@ -140,7 +178,56 @@ func Run(loader packages.Loader, runner run.Runner, args []string) error {
 		Args:              args,
 		Environment:       expandEnvVars(pkg.Environment),
 		Volumes:           append(volumes, parseRuntimeVolumes(args, pkg)...),
-	})
+		Runtime:           pkg.Runtime,
+		CPUs:              pkg.Resources.CPUs,
+		Memory:            pkg.Resources.Memory,
+		PidsLimit:         pkg.Resources.PidsLimit,
+		ReadOnly:          pkg.Security.ReadOnly,
+		CapDrop:           pkg.Security.CapDrop,
+		NoNewPrivileges:   pkg.Security.NoNewPrivileges,
+	}
+	// the package may have been installed before the policy denied it,
+	// and its arguments and working directory mount more paths than the ones it declares
+	if err := checkRunPolicy(pkg, e); err != nil {
+		return err
+	}
+	return runWithHooks(runner, pkg, e)
+}
+
+// runWithHooks runs the execution of a package between its pre-run and post-run hooks.
+// When post-run hooks are installed, the execution is waited for to report its exit status and duration.
+func runWithHooks(runner run.Runner, pkg *packages.Package, e *run.Execution) error {
+	payload := hooks.Payload{Package: pkg, Execution: e}
+	if err := hooks.Run("pre-run", payload); err != nil {
+		return fmt.Errorf("pre-run script failed: %s", err.Error())
+	}
+	if !hooks.Has("post-run") {
+		return runner.Run(e)
+	}
+
+	e.Wait = true
+	start := time.Now()
+	err := runner.Run(e)
+	payload.Duration = time.Since(start).Seconds()
+	status := 0
+	var exitErr run.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		status = exitErr.Code
+		payload.ExitStatus = &status
+	case err != nil:
+		payload.Error = err.Error()
+	default:
+		payload.ExitStatus = &status
+	}
+	if hookErr := hooks.Run("post-run", payload); hookErr != nil {
+		if err == nil {
+			return fmt.Errorf("post-run script failed: %s", hookErr.Error())
+		}
+		// keep the exit status of the package
+		fmt.Fprintf(os.Stderr, "post-run script failed: %s\n", hookErr.Error())
+	}
+	return err
 }
 
 // IsShellbang returns whether the arguments should be interpreted as a shellbang run
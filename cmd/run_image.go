@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Songmu/prompter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+func init() {
+	runCommand.Flags().StringVarP(&customPackageName, "name", "n", "", "Name to give the package. Defaults to image name.")
+	runCommand.Flags().StringVarP(&customEntrypoint, "entrypoint", "e", "", "Custom entrypoint to run the image with. Defaults to image entrypoint.")
+	runCommand.Flags().BoolVarP(&assumeYes, "assume-yes", "y", false, "Assume 'yes' as answer to all prompts and run non-interactively. Defaults to false.")
+	runCommand.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "yes" {
+			name = "assume-yes"
+		}
+		return pflag.NormalizedName(name)
+	})
+	// Flags after the image name belong to the command run in the container
+	runCommand.Flags().SetInterspersed(false)
+
+	RootCmd.AddCommand(runCommand)
+}
+
+// packageFromImage builds the package of an image without installing it
+func packageFromImage(inspecter run.ImageInspecter, imageName string) (*packages.Package, error) {
+	imageInspect, err := inspecter.ImageInspect(imageName)
+	if err != nil {
+		return nil, err
+	}
+	if err := lintForInstall(imageInspect, customEntrypoint); err != nil {
+		return nil, err
+	}
+	pkg, err := packages.NewPackageFromImage(imageName, imageInspect)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.WorkingDir == "" {
+		pkg.WorkingDir = packages.DefaultWorkingDir
+	}
+	if customPackageName != "" {
+		pkg.Name = customPackageName
+	}
+	if customEntrypoint != "" {
+		pkg.Entrypoint = []string{customEntrypoint}
+	}
+	return pkg, nil
+}
+
+var runCommand = &cobra.Command{
+	Use:   "run IMAGENAME [-- ARGS...]",
+	Short: "Run an image as a package without installing it",
+	Long:  "Run an image once, the way it would run if it was installed, without writing anything to the installation path.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return cmd.Help()
+		}
+		docker, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return runImage(docker, docker, packageRunner{}, args)
+	},
+}
+
+// runImage runs the image named by the first argument as a package, with the following arguments
+func runImage(inspecter run.ImageInspecter, digester run.ImageDigester, runner run.Runner, args []string) error {
+	imageName, args := args[0], args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	pkg, err := packageFromImage(inspecter, imageName)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPolicy(pkg); err != nil {
+		return err
+	}
+	preinstallMessage := pkg.PreinstallMessage(nil)
+	if preinstallMessage != "" {
+		// keep the standard output for the command being run
+		fmt.Fprintln(os.Stderr, preinstallMessage)
+		if !assumeYes {
+			if !prompter.YN("Is this okay?", true) {
+				return fmt.Errorf("Not running package")
+			}
+		}
+	}
+
+	// images run once are not installed, so they are verified whether or not installed packages are verified on run
+	image, err := verifyLocalImage(digester, pkg.Image, false)
+	if err != nil {
+		return err
+	}
+	return runPackage(runner, pkg, image, args)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+type fakeInspecter map[string]*imagev1.Image
+
+func (f fakeInspecter) ImageInspect(imageName string) (*imagev1.Image, error) {
+	if image, ok := f[imageName]; ok {
+		return image, nil
+	}
+	return nil, fmt.Errorf("no such image %s", imageName)
+}
+
+// noDigests lists no digest for local images, as if they were built locally
+var noDigests = testDigester(func(string) ([]string, error) { return nil, nil })
+
+func TestRunImage(t *testing.T) {
+	installPath := t.TempDir()
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
+	t.Setenv("WHALEBREW_INSTALL_PATH", installPath)
+	config.Reset()
+	t.Cleanup(config.Reset)
+	t.Cleanup(func() {
+		customPackageName, customEntrypoint, assumeYes = "", "", false
+	})
+
+	inspecter := fakeInspecter{
+		"whalebrew/jq": {Config: imagev1.ImageConfig{
+			Entrypoint: []string{"jq"},
+			Labels:     map[string]string{"io.whalebrew.config.environment": `["JQ_COLORS"]`},
+		}},
+		"whalebrew/no-entrypoint": {},
+	}
+
+	t.Run("arguments after -- are given to the package", func(t *testing.T) {
+		require.NoError(t, runCommand.Flags().Parse([]string{"--yes", "whalebrew/jq", "--", "-r", ".name"}))
+		assert.True(t, assumeYes)
+		executed := false
+		assert.NoError(t, runImage(inspecter, noDigests, runnerFunc(func(e *run.Execution) error {
+			executed = true
+			assert.Equal(t, "whalebrew/jq", e.Image)
+			assert.Equal(t, []string{"-r", ".name"}, e.Args)
+			assert.Contains(t, e.Environment, "JQ_COLORS")
+			return nil
+		}), runCommand.Flags().Args()))
+		assert.True(t, executed)
+	})
+
+	t.Run("the name and entrypoint can be overridden", func(t *testing.T) {
+		require.NoError(t, runCommand.Flags().Parse([]string{"--name", "my-tool", "--entrypoint", "/bin/tool", "whalebrew/no-entrypoint", "--version"}))
+		pkg, err := packageFromImage(inspecter, "whalebrew/no-entrypoint")
+		require.NoError(t, err)
+		assert.Equal(t, "my-tool", pkg.Name)
+		assert.Equal(t, []string{"/bin/tool"}, pkg.Entrypoint)
+		assert.NoError(t, runImage(inspecter, noDigests, runnerFunc(func(e *run.Execution) error {
+			assert.Equal(t, []string{"/bin/tool"}, e.Entrypoint)
+			assert.Equal(t, []string{"--version"}, e.Args)
+			return nil
+		}), runCommand.Flags().Args()))
+	})
+
+	t.Run("images that are not packages are not run", func(t *testing.T) {
+		customEntrypoint = ""
+		assert.Error(t, runImage(inspecter, noDigests, runnerFunc(func(e *run.Execution) error {
+			t.Error("the image should not run")
+			return nil
+		}), []string{"whalebrew/no-entrypoint"}))
+		assert.Error(t, runImage(inspecter, noDigests, runnerFunc(func(e *run.Execution) error {
+			t.Error("the image should not run")
+			return nil
+		}), []string{"whalebrew/missing"}))
+	})
+
+	entries, err := os.ReadDir(installPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing must be written to the install path")
+}
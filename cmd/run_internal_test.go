@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
 )
 
 func TestShouldBind(t *testing.T) {
@@ -16,35 +18,72 @@ func TestShouldBind(t *testing.T) {
 	assert.NoError(t, err)
 	t.Run("with a file that exists", func(t *testing.T) {
 		t.Run("when not skipping missing volumes", func(t *testing.T) {
-			bind, err := shouldBind(filepath.Join(wd, "run.go"), &packages.Package{SkipMissingVolumes: false})
+			bind, err := shouldBind(packages.Volume{Host: filepath.Join(wd, "run.go"), Container: "/run.go"}, &packages.Package{SkipMissingVolumes: false})
 			assert.NoError(t, err)
 			assert.True(t, bind)
 		})
 		t.Run("when skipping missing volumes", func(t *testing.T) {
-			bind, err := shouldBind(filepath.Join(wd, "run.go"), &packages.Package{SkipMissingVolumes: true})
+			bind, err := shouldBind(packages.Volume{Host: filepath.Join(wd, "run.go"), Container: "/run.go"}, &packages.Package{SkipMissingVolumes: true})
 			assert.NoError(t, err)
 			assert.True(t, bind)
 		})
 	})
 	t.Run("with a file that does not exists", func(t *testing.T) {
 		t.Run("when not skipping missing volumes", func(t *testing.T) {
-			bind, err := shouldBind(filepath.Join(wd, "thisFileShouldNotExist.go"), &packages.Package{SkipMissingVolumes: false})
+			bind, err := shouldBind(packages.Volume{Host: filepath.Join(wd, "thisFileShouldNotExist.go"), Container: "/thisFileShouldNotExist.go"}, &packages.Package{SkipMissingVolumes: false})
 			assert.Error(t, err)
 			assert.False(t, bind)
 		})
 		t.Run("when skipping missing volumes", func(t *testing.T) {
-			bind, err := shouldBind(filepath.Join(wd, "thisFileShouldNotExist.go"), &packages.Package{SkipMissingVolumes: true})
+			bind, err := shouldBind(packages.Volume{Host: filepath.Join(wd, "thisFileShouldNotExist.go"), Container: "/thisFileShouldNotExist.go"}, &packages.Package{SkipMissingVolumes: true})
 			assert.NoError(t, err)
 			assert.False(t, bind)
 		})
 		t.Run("when mounting missing volumes", func(t *testing.T) {
-			bind, err := shouldBind(filepath.Join(wd, "thisFileShouldNotExist.go"), &packages.Package{MountMissingVolumes: true})
+			bind, err := shouldBind(packages.Volume{Host: filepath.Join(wd, "thisFileShouldNotExist.go"), Container: "/thisFileShouldNotExist.go"}, &packages.Package{MountMissingVolumes: true})
 			assert.NoError(t, err)
 			assert.True(t, bind)
 		})
 	})
 }
 
+func TestShouldBindVolumes(t *testing.T) {
+	for _, volume := range []packages.Volume{
+		{Container: "/anonymous"},
+		{Host: "named", Container: "/named"},
+		{Host: "relative/path", Container: "/relative"},
+	} {
+		bind, err := shouldBind(volume, &packages.Package{})
+		assert.NoError(t, err)
+		assert.Truef(t, bind, "volume %s should be bound", volume)
+	}
+}
+
+type testVolumeCreator func(name string, labels map[string]string) error
+
+func (tvc testVolumeCreator) VolumeCreate(name string, labels map[string]string) error {
+	return tvc(name, labels)
+}
+
+func TestCreateNamedVolumes(t *testing.T) {
+	created := []string{}
+	creator := testVolumeCreator(func(name string, labels map[string]string) error {
+		assert.Equal(t, map[string]string{"io.whalebrew.image": "whalebrew/npm"}, labels)
+		created = append(created, name)
+		return nil
+	})
+	assert.NoError(t, createNamedVolumes(creator, &run.Execution{
+		Image:   "whalebrew/npm",
+		Volumes: []string{"/tmp:/tmp", "/anonymous", "npm-cache:/root/.npm", "other-cache:/cache:ro"},
+	}))
+	assert.Equal(t, []string{"npm-cache", "other-cache"}, created)
+
+	creator = testVolumeCreator(func(name string, labels map[string]string) error {
+		return errors.New("test error")
+	})
+	assert.Error(t, createNamedVolumes(creator, &run.Execution{Volumes: []string{"npm-cache:/root/.npm"}}))
+}
+
 func TestAppendVolumes(t *testing.T) {
 	wd, err := os.Getwd()
 	assert.NoError(t, err)
@@ -1,13 +1,16 @@
 package cmd_test
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/whalebrew/whalebrew/cmd"
+	"github.com/whalebrew/whalebrew/config"
 	"github.com/whalebrew/whalebrew/packages"
 	"github.com/whalebrew/whalebrew/run"
 )
@@ -18,6 +21,17 @@ func (tr testRunner) Run(e *run.Execution) error {
 	return tr(e)
 }
 
+type testDigester func(imageName string) ([]string, error)
+
+func (td testDigester) ImageRepoDigests(imageName string) ([]string, error) {
+	return td(imageName)
+}
+
+// noDigests lists no digest for local images, as if they were built locally
+func noDigests(string) (run.ImageDigester, error) {
+	return testDigester(func(string) ([]string, error) { return nil, nil }), nil
+}
+
 type testLoader func(path string) (*packages.Package, error)
 
 func (tl testLoader) LoadPackageFromPath(path string) (*packages.Package, error) {
@@ -42,18 +56,36 @@ func TestRun(t *testing.T) {
 	f := func(e *run.Execution) error {
 		return errors.New("test error")
 	}
-	assert.Error(t, cmd.Run(packages.DefaultLoader, testRunner(f), []string{"whalebrew", "../packages/resources/aws"}))
+	assert.Error(t, cmd.Run(packages.DefaultLoader, testRunner(f), noDigests, []string{"whalebrew", "../packages/resources/aws"}))
 	os.Setenv("TEST_ENVIRONMENT_VARIABLE", "SOME-VALUE")
 	f = func(e *run.Execution) error {
 		assert.Contains(t, e.Environment, "TEST_ENV=SOME-VALUE")
 		assert.Equal(t, 2, len(e.Volumes))
 		return nil
 	}
-	assert.NoError(t, cmd.Run(packages.DefaultLoader, testRunner(f), []string{"whalebrew", "../packages/resources/aws"}))
+	assert.NoError(t, cmd.Run(packages.DefaultLoader, testRunner(f), noDigests, []string{"whalebrew", "../packages/resources/aws"}))
 	f = func(e *run.Execution) error {
 		return nil
 	}
-	assert.Error(t, cmd.Run(packages.DefaultLoader, testRunner(f), []string{"whalebrew", "./this-package-does-not-exist", "arg1"}))
+	assert.Error(t, cmd.Run(packages.DefaultLoader, testRunner(f), noDigests, []string{"whalebrew", "./this-package-does-not-exist", "arg1"}))
+}
+
+func TestRunUsesTheDigesterOfThePackageRuntime(t *testing.T) {
+	err := cmd.Run(
+		testLoader(func(string) (*packages.Package, error) {
+			return &packages.Package{Image: "whalebrew/jq", Runtime: "podman"}, nil
+		}),
+		testRunner(func(e *run.Execution) error {
+			t.Error("the package should not run without a digester")
+			return nil
+		}),
+		func(runtime string) (run.ImageDigester, error) {
+			assert.Equal(t, "podman", runtime)
+			return nil, errors.New("no such runtime")
+		},
+		[]string{"whalebrew", "/usr/local/bin/jq"},
+	)
+	assert.EqualError(t, err, "no such runtime")
 }
 
 func TestRunWorkdirIsExpanded(t *testing.T) {
@@ -68,6 +100,7 @@ func TestRunWorkdirIsExpanded(t *testing.T) {
 				assert.Equal(t, "/homes/test-user", e.WorkingDir)
 				return nil
 			}),
+			noDigests,
 			[]string{"whalebrew", "/usr/local/bin/pkg"},
 		),
 	)
@@ -90,6 +123,7 @@ func TestRunVolumesIsExpanded(t *testing.T) {
 				assert.Equal(t, "/workdir", v[1])
 				return nil
 			}),
+			noDigests,
 			[]string{"whalebrew", "/usr/local/bin/pkg"},
 		),
 	)
@@ -111,6 +145,7 @@ func TestRunCLIVolumesIsConsidered(t *testing.T) {
 				assert.Equal(t, []string{"/bla:/bla", wd + "/hello-world:" + wd + "/hello-world"}, e.Volumes[1:])
 				return nil
 			}),
+			noDigests,
 			[]string{"whalebrew", "/usr/local/bin/pkg", "-c", "/bla", "--change-dir", "hello-world"},
 		),
 	)
@@ -130,7 +165,61 @@ func TestRunEnvironmentIsExpanded(t *testing.T) {
 				assert.Equal(t, []string{"HOME=./resources"}, e.Environment)
 				return nil
 			}),
+			noDigests,
 			[]string{"whalebrew", "/usr/local/bin/pkg"},
 		),
 	)
 }
+
+func writeHook(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+content), 0755))
+}
+
+func TestRunHooks(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHALEBREW_CONFIG_DIR", configDir)
+	t.Setenv("WHALEBREW_INSTALL_PATH", t.TempDir())
+	config.Reset()
+	t.Cleanup(config.Reset)
+	out := filepath.Join(configDir, "out")
+	args := []string{"whalebrew", "../packages/resources/aws", "s3", "ls"}
+
+	t.Run("without post-run hooks, the package replaces whalebrew", func(t *testing.T) {
+		writeHook(t, filepath.Join(configDir, "hooks", "pre-run"), "cat > "+out+"\n")
+		assert.NoError(t, cmd.Run(packages.DefaultLoader, testRunner(func(e *run.Execution) error {
+			assert.False(t, e.Wait)
+			return nil
+		}), noDigests, args))
+		payload := struct {
+			Hook      string        `json:"hook"`
+			Execution run.Execution `json:"execution"`
+		}{}
+		content, err := os.ReadFile(out)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(content, &payload))
+		assert.Equal(t, "pre-run", payload.Hook)
+		assert.Equal(t, []string{"s3", "ls"}, payload.Execution.Args)
+	})
+
+	t.Run("with post-run hooks, the exit status is reported", func(t *testing.T) {
+		writeHook(t, filepath.Join(configDir, "hooks", "post-run.d", "10-log"), "echo $WHALEBREW_PACKAGE_NAME $WHALEBREW_EXIT_STATUS > "+out+"\n")
+		err := cmd.Run(packages.DefaultLoader, testRunner(func(e *run.Execution) error {
+			assert.True(t, e.Wait)
+			return run.ExitError{Code: 2}
+		}), noDigests, args)
+		assert.Equal(t, run.ExitError{Code: 2}, err)
+		content, err := os.ReadFile(out)
+		assert.NoError(t, err)
+		assert.Equal(t, "aws 2\n", string(content))
+	})
+
+	t.Run("when the pre-run hook fails, the package does not run", func(t *testing.T) {
+		writeHook(t, filepath.Join(configDir, "hooks", "pre-run"), "exit 1\n")
+		assert.Error(t, cmd.Run(packages.DefaultLoader, testRunner(func(e *run.Execution) error {
+			t.Error("the package should not run")
+			return nil
+		}), noDigests, args))
+	})
+}
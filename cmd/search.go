@@ -1,16 +1,97 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+	"github.com/whalebrew/whalebrew/run"
 	"github.com/whalebrew/whalebrew/search"
 )
 
+const defaultSearchTimeout = 30 * time.Second
+
+var (
+	searchOutput  string
+	searchDetails bool
+	searchTags    bool
+	searchTimeout time.Duration
+	packagesOnly  bool
+	searchRefresh bool
+	searchOffline bool
+	searchMaxAge  time.Duration
+	tagsOutput    string
+)
+
 func init() {
+	addOutputFlag(searchCommand, &searchOutput)
+	searchCommand.Flags().BoolVarP(&searchDetails, "details", "d", false, "Show the description, stars, pulls and last update of images.")
+	searchCommand.Flags().BoolVar(&searchTags, "tags", false, "Show the details and the tags of images.")
+	searchCommand.Flags().BoolVar(&packagesOnly, "packages-only", false, "Only show images that are valid whalebrew packages, with the permissions they request. The configuration of each image is read from the index, or from its registry when it is not indexed.")
+	searchCommand.Flags().DurationVar(&searchTimeout, "timeout", defaultSearchTimeout, "Time to wait for registries to answer.")
+	searchCommand.Flags().BoolVar(&searchRefresh, "refresh", false, "Update the local search index before searching it.")
+	searchCommand.Flags().BoolVar(&searchOffline, "offline", false, "Only search the local search index, whatever its age.")
+	searchCommand.Flags().DurationVar(&searchMaxAge, "max-age", search.DefaultIndexMaxAge, "Age after which registries are searched again instead of the local search index.")
+	addOutputFlag(tagsCommand, &tagsOutput)
+	tagsCommand.Flags().DurationVar(&searchTimeout, "timeout", defaultSearchTimeout, "Time to wait for the registry to answer.")
+
 	RootCmd.AddCommand(searchCommand)
+	RootCmd.AddCommand(tagsCommand)
+}
+
+// formatOptionalTime formats t, empty when it is not known
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+// searcherFor returns the searcher of the registry hosting the image
+func searcherFor(ref dockerregistry.Reference) search.Searcher {
+	if ref.IsDockerHub() {
+		return &search.DockerHub{}
+	}
+	return &search.DockerRegistry{Registry: registryFor(ref)}
+}
+
+func writeSearchDetails(w io.Writer, results []search.Result, withDetails, withTags, withPermissions bool) error {
+	tw := tabwriter.NewWriter(w, 10, 2, 2, ' ', 0)
+	header := "IMAGE"
+	if withDetails {
+		header += "\tSTARS\tPULLS\tLAST UPDATED\tDESCRIPTION"
+	}
+	if withTags {
+		header += "\tTAGS"
+	}
+	if withPermissions {
+		header += "\tPERMISSIONS"
+	}
+	fmt.Fprintln(tw, header)
+	for _, result := range results {
+		line := result.Image
+		if withDetails {
+			line += fmt.Sprintf("\t%d\t%d\t%s\t%s", result.Stars, result.Pulls, formatOptionalTime(result.LastUpdated), result.Description)
+		}
+		if withTags {
+			names := []string{}
+			for _, tag := range result.Tags {
+				names = append(names, tag.Name)
+			}
+			line += "\t" + strings.Join(names, ",")
+		}
+		if withPermissions && result.Permissions != nil {
+			line += "\t" + result.Permissions.String()
+		}
+		fmt.Fprintln(tw, line)
+	}
+	return tw.Flush()
 }
 
 var searchCommand = &cobra.Command{
@@ -21,16 +102,101 @@ var searchCommand = &cobra.Command{
 		if len(args) != 1 {
 			return cmd.Help()
 		}
-		errHandler := func(err error) bool {
-			fmt.Println(err.Error())
-			os.Exit(1)
-			return true
+		if err := validateOutputFormat(searchOutput); err != nil {
+			return err
+		}
+		if searchRefresh && searchOffline {
+			return fmt.Errorf("--refresh can not be used together with --offline")
 		}
-		for searcher := range search.ForRegistries(config.GetConfig().Registries, errHandler) {
-			for image := range searcher.Search(args[0], errHandler) {
-				fmt.Println(image)
+		live, errs := configuredSearchers()
+		idx, err := search.LoadIndex(search.IndexPath())
+		if err != nil {
+			return err
+		}
+		if searchRefresh {
+			if err := refreshIndex(os.Stderr, idx, live, searchTimeout); err != nil {
+				return err
 			}
 		}
-		return nil
+		searchers := []search.Searcher{}
+		for _, searcher := range live {
+			searchers = append(searchers, &search.Cached{Searcher: searcher, Index: idx, MaxAge: searchMaxAge, Offline: searchOffline})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+		defer cancel()
+		results := search.Search(ctx, searchers, args[0], func(err error) {
+			errs = append(errs, err)
+		})
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "❗️  %v\n", err)
+		}
+		if len(errs) > 0 && len(results) == 0 {
+			return fmt.Errorf("unable to search the registries")
+		}
+		if packagesOnly {
+			var inspecter run.ImageInspecter
+			if !searchOffline {
+				inspecter = remoteInspecter()
+			}
+			results = search.Packages(inspecter, results, func(err error) {
+				fmt.Fprintf(os.Stderr, "❗️  unable to inspect %v\n", err)
+			})
+		}
+		if searchTags {
+			for i, result := range results {
+				ref, err := dockerregistry.ParseReference(result.Image)
+				if err != nil {
+					return err
+				}
+				tags, err := searcherFor(ref).Tags(ctx, result.Image)
+				if err != nil {
+					return fmt.Errorf("unable to list the tags of %s: %w", result.Image, err)
+				}
+				results[i].Tags = tags
+			}
+		}
+		return writeOutput(os.Stdout, searchOutput, results, func(w io.Writer) error {
+			if searchDetails || searchTags || packagesOnly {
+				return writeSearchDetails(w, results, searchDetails || searchTags, searchTags, packagesOnly)
+			}
+			for _, result := range results {
+				if _, err := fmt.Fprintln(w, result.Image); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	},
+}
+
+var tagsCommand = &cobra.Command{
+	Use:   "tags IMAGE",
+	Short: "List the tags of an image",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return cmd.Help()
+		}
+		if err := validateOutputFormat(tagsOutput); err != nil {
+			return err
+		}
+		ref, err := dockerregistry.ParseReference(args[0])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+		defer cancel()
+		tags, err := searcherFor(ref).Tags(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return writeOutput(os.Stdout, tagsOutput, tags, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 10, 2, 2, ' ', 0)
+			fmt.Fprintln(tw, "TAG\tLAST UPDATED\tDIGEST")
+			for _, tag := range tags {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", tag.Name, formatOptionalTime(tag.LastUpdated), tag.Digest)
+			}
+			return tw.Flush()
+		})
 	},
 }
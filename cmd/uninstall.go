@@ -46,9 +46,6 @@ var uninstallCommand = &cobra.Command{
 		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
 		packageNameOrImage := args[0]
 
-		if err := hooks.Run("pre-uninstall", packageNameOrImage); err != nil {
-			return fmt.Errorf("pre-uninstall install script failed: %s", err.Error())
-		}
 		packages, err := pm.List()
 		if err != nil {
 			return fmt.Errorf("unable to list packages: %v", err)
@@ -80,6 +77,11 @@ var uninstallCommand = &cobra.Command{
 			return nil
 		}
 
+		payload := hooks.Payload{Package: candidates[0].pkg}
+		if err := hooks.Run("pre-uninstall", payload, packageNameOrImage); err != nil {
+			return fmt.Errorf("pre-uninstall install script failed: %s", err.Error())
+		}
+
 		path := path.Join(pm.InstallPath, candidates[0].pkg.Name)
 		if !assumeYes {
 			if !prompter.YN(fmt.Sprintf("This will permanently delete '%s'. Are you sure?", path), false) {
@@ -91,8 +93,9 @@ var uninstallCommand = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		unlockPackage(candidates[0].pkg.Name)
 
-		if err := hooks.Run("post-uninstall", packageNameOrImage); err != nil {
+		if err := hooks.Run("post-uninstall", payload, packageNameOrImage); err != nil {
 			return fmt.Errorf("post-uninstall install script failed: %s", err.Error())
 		}
 		fmt.Printf("🚽  Uninstalled %s\n", path)
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/search"
+)
+
+var updateIndexTimeout time.Duration
+
+func init() {
+	updateIndexCommand.Flags().DurationVar(&updateIndexTimeout, "timeout", defaultSearchTimeout, "Time to wait for each registry to list and inspect its images.")
+
+	RootCmd.AddCommand(updateIndexCommand)
+}
+
+// configuredSearchers returns the searchers of the configured registries and the configuration errors
+func configuredSearchers() ([]search.Searcher, []error) {
+	errs := []error{}
+	searchers := []search.Searcher{}
+	for searcher := range search.ForRegistries(config.GetConfig().Registries, func(err error) {
+		errs = append(errs, err)
+	}) {
+		searchers = append(searchers, searcher)
+	}
+	return searchers, errs
+}
+
+// refreshIndex indexes the images of the searchers and their labels, and saves the index.
+// timeout bounds both the listing and the inspection of the images of each registry, images left to inspect are indexed without labels.
+// Registries that can not be indexed are reported on stderr, it fails only when none could be indexed.
+func refreshIndex(w io.Writer, idx *search.Index, searchers []search.Searcher, timeout time.Duration) error {
+	indexed := 0
+	for _, searcher := range searchers {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		source, err := idx.Refresh(ctx, searcher, remoteInspecter(), func(err error) {
+			fmt.Fprintf(os.Stderr, "❗️  unable to inspect %v\n", err)
+		})
+		cancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❗️  %v: %v\n", searcher, err)
+			continue
+		}
+		indexed++
+		fmt.Fprintf(w, "🐳  Indexed %d images of %v\n", len(source.Results), searcher)
+	}
+	if indexed == 0 && len(searchers) > 0 {
+		return fmt.Errorf("unable to index the registries")
+	}
+	return idx.Save()
+}
+
+var updateIndexCommand = &cobra.Command{
+	Use:   "update-index",
+	Short: "Update the local search index",
+	Long:  "Index the images of the configured registries and their whalebrew labels, so they can be searched quickly and offline.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		searchers, errs := configuredSearchers()
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "❗️  %v\n", err)
+		}
+		idx, err := search.LoadIndex(search.IndexPath())
+		if err != nil {
+			return err
+		}
+		return refreshIndex(os.Stdout, idx, searchers, updateIndexTimeout)
+	},
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Songmu/prompter"
+	"github.com/spf13/cobra"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+var upgradeAll bool
+
+func init() {
+	upgradeCommand.Flags().BoolVarP(&upgradeAll, "all", "a", false, "Upgrade all installed packages. Defaults to false.")
+	upgradeCommand.Flags().BoolVarP(&assumeYes, "assume-yes", "y", false, "Assume 'yes' as answer to all prompts and run non-interactively. Defaults to false.")
+
+	RootCmd.AddCommand(upgradeCommand)
+}
+
+var upgradeCommand = &cobra.Command{
+	Use:   "upgrade [PACKAGENAME...|--all]",
+	Short: "Upgrade installed packages",
+	Long:  "Pull the latest version of the images of installed packages and re-generate the packages when the image configuration changed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !upgradeAll {
+			return cmd.Help()
+		}
+		if len(args) > 0 && upgradeAll {
+			return fmt.Errorf("package names can not be provided together with --all")
+		}
+
+		pm := packages.NewPackageManager(config.GetConfig().InstallPath)
+		installed, err := pm.List()
+		if err != nil {
+			return fmt.Errorf("unable to list packages: %v", err)
+		}
+
+		names := args
+		if upgradeAll {
+			names = make([]string, 0, len(installed))
+			for name := range installed {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		upgrades := []*packages.Package{}
+		upToDate := []string{}
+		for _, name := range names {
+			pkg, ok := installed[name]
+			if !ok {
+				return fmt.Errorf("package %s is not installed in %s", name, pm.InstallPath)
+			}
+			docker, err := newEngineFor(pkg.Runtime)
+			if err != nil {
+				return err
+			}
+			if err := docker.ImagePull(pkg.Image); err != nil {
+				return err
+			}
+			upgraded, changed, diff, err := pkg.Upgrade(docker)
+			if err != nil {
+				return fmt.Errorf("unable to upgrade %s: %v", name, err)
+			}
+			if !changed {
+				upToDate = append(upToDate, name)
+				continue
+			}
+			fmt.Printf("📦  %s (%s) changed:\n", name, pkg.Image)
+			fmt.Println(diff)
+			if err := checkPolicy(upgraded); err != nil {
+				return err
+			}
+			if message := upgraded.PreinstallMessage(pkg); message != "" {
+				fmt.Println(message)
+			}
+			upgrades = append(upgrades, upgraded)
+		}
+
+		for _, name := range upToDate {
+			fmt.Printf("✅  %s is already up to date\n", name)
+		}
+		if len(upgrades) == 0 {
+			return nil
+		}
+
+		if !assumeYes {
+			if !prompter.YN(fmt.Sprintf("Would you like to upgrade %d package(s)?", len(upgrades)), true) {
+				return fmt.Errorf("Not upgrading packages")
+			}
+		}
+
+		for _, pkg := range upgrades {
+			docker, err := newEngineFor(pkg.Runtime)
+			if err != nil {
+				return err
+			}
+			if err := writePackage(pm, docker, pkg.Image, pkg, true, packages.WithSource("upgrade")); err != nil {
+				return err
+			}
+			fmt.Printf("🐳  Upgraded %s\n", pkg.Name)
+		}
+		return nil
+	},
+}
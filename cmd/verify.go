@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+	"github.com/whalebrew/whalebrew/run"
+	"github.com/whalebrew/whalebrew/signature"
+)
+
+// verificationRule returns the rule the image must be verified with, nil when it does not need to be verified.
+// When running packages, only the rules verifying them on run apply.
+func verificationRule(image string, onRun bool) (*config.Verify, dockerregistry.Reference, error) {
+	rules := config.GetConfig().Verify
+	if len(rules) == 0 {
+		return nil, dockerregistry.Reference{}, nil
+	}
+	ref, err := dockerregistry.ParseReference(image)
+	if err != nil {
+		return nil, ref, err
+	}
+	rule := signature.RuleFor(rules, ref)
+	if rule == nil || (onRun && !rule.OnRun) {
+		return nil, ref, nil
+	}
+	return rule, ref, nil
+}
+
+// verifyImage checks the signature of the image when the configuration requires it for its registry or owner,
+// and returns the verified digest, empty when the image did not need to be verified.
+// digest is the digest of the image to verify, the one its tag points to in the registry when empty.
+// When running packages, only the images of rules verifying them on run are verified.
+func verifyImage(image, digest string, onRun bool) (string, error) {
+	rule, ref, err := verificationRule(image, onRun)
+	if err != nil || rule == nil {
+		return "", err
+	}
+	keys, err := signature.Keys(rule, config.ConfigDir())
+	if err != nil {
+		return "", err
+	}
+	if digest != "" {
+		ref.Digest = digest
+	}
+	verifier := &signature.Verifier{Registry: registryFor(ref), Keys: keys}
+	verified, err := verifier.Verify(ref)
+	if err != nil {
+		return "", fmt.Errorf("unable to verify the signature of %s: %w", image, err)
+	}
+	return verified, nil
+}
+
+// verifyLocalImage checks the signature of the local image docker runs for image, when the configuration requires it,
+// and returns image pinned to the verified digest, so that the image running is the one verified.
+func verifyLocalImage(digester run.ImageDigester, image string, onRun bool) (string, error) {
+	rule, ref, err := verificationRule(image, onRun)
+	if err != nil || rule == nil {
+		return image, err
+	}
+	digest, err := imageDigest(digester, image)
+	if err != nil {
+		return "", fmt.Errorf("unable to verify the signature of %s: %w", image, err)
+	}
+	if _, err := verifyImage(image, digest, onRun); err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return image, nil
+	}
+	return image + "@" + digest, nil
+}
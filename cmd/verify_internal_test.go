@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/config"
+)
+
+func TestVerifyImage(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	dir := t.TempDir()
+	t.Setenv("WHALEBREW_CONFIG_DIR", dir)
+	config.Reset()
+	defer config.Reset()
+	verified, err := verifyImage(host+"/whalebrew/jq", "", false)
+	assert.NoError(t, err)
+	assert.Empty(t, verified)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cosign.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(`
+registries:
+- dockerRegistry:
+    host: `+host+`
+    useHTTP: true
+verify:
+- registry: `+host+`/whalebrew
+  keys: [cosign.pub]
+- registry: `+host+`/other
+  keys: [missing.pub]
+  on_run: true
+`), 0644))
+	config.Reset()
+
+	_, err = verifyImage(host+"/whalebrew/jq", "", false)
+	assert.Error(t, err, "unsigned images must be refused")
+	verified, err = verifyImage(host+"/whalebrew/jq", "", true)
+	assert.NoError(t, err, "images are only verified on run when required")
+	assert.Empty(t, verified)
+	_, err = verifyImage(host+"/other/jq", "", true)
+	assert.Error(t, err)
+	verified, err = verifyImage("whalebrew/jq", "", false)
+	assert.NoError(t, err)
+	assert.Empty(t, verified)
+
+	image, err := verifyLocalImage(testDigester(func(string) ([]string, error) {
+		t.Error("images that do not need to be verified are not looked up")
+		return nil, nil
+	}), host+"/whalebrew/jq", true)
+	assert.NoError(t, err)
+	assert.Equal(t, host+"/whalebrew/jq", image)
+	_, err = verifyLocalImage(testDigester(func(string) ([]string, error) {
+		return []string{host + "/whalebrew/jq@sha256:1234"}, nil
+	}), host+"/whalebrew/jq", false)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "sha256:1234", "the digest of the local image is verified")
+	}
+	_, err = verifyLocalImage(testDigester(func(string) ([]string, error) {
+		return nil, errors.New("no such image")
+	}), host+"/whalebrew/jq", false)
+	assert.Error(t, err)
+}
@@ -5,14 +5,21 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 const (
 	configPath = "config.yaml"
+
+	// RunnerCLI runs packages using the docker or podman command line
+	RunnerCLI = "cli"
+	// RunnerAPI runs packages talking directly to the docker engine API
+	RunnerAPI = "api"
 )
 
 var (
@@ -35,10 +42,41 @@ type Registry struct {
 	DockerRegistry *DockerRegistry    `yaml:"dockerRegistry"`
 }
 
+// Runtime configures the docker like command line used by the cli runner
+type Runtime struct {
+	Path    string   `yaml:"path"`
+	Flavour string   `yaml:"flavour"`
+	Args    []string `yaml:"args"`
+}
+
+// Hooks configures how the scripts of hooks run
+type Hooks struct {
+	// ContinueOnError runs the following scripts of a hook when one fails, the hook failing once all ran
+	ContinueOnError bool `yaml:"continue_on_error"`
+	// Timeout stops the scripts running for longer, when set
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Verify requires the images of a registry or owner to be signed with one of the keys
+type Verify struct {
+	// Registry is the registry the images are hosted in, optionally followed by their owner, like docker.io/whalebrew or ghcr.io
+	Registry string `yaml:"registry"`
+	// Keys are paths to PEM encoded public keys, relative to the configuration directory when not absolute
+	Keys []string `yaml:"keys"`
+	// OnRun verifies the signature of images again each time packages run
+	OnRun bool `yaml:"on_run"`
+}
+
 type Config struct {
 	InstallPath          string     `yaml:"install_path" env:"install_path" mapstructure:"install_path"`
 	Registries           []Registry `yaml:"registries"`
+	Locked               bool       `yaml:"locked"`
+	Runner               string     `yaml:"runner"`
+	Runtime              Runtime    `yaml:"runtime"`
+	Hooks                Hooks      `yaml:"hooks"`
+	Verify               []Verify   `yaml:"verify"`
 	isDefaultInstallPath bool
+	isRuntimeFromEnv     bool
 }
 
 func parseYaml(path string, out interface{}) error {
@@ -84,12 +122,22 @@ func (c Config) IsDefaultInstallPath() bool {
 	return c.isDefaultInstallPath
 }
 
+// PackageRuntime returns the runtime to run a package requiring the given runtime with.
+// The runtime provided in WHALEBREW_RUNTIME takes precedence over the one of the package.
+func (c Config) PackageRuntime(runtime string) Runtime {
+	if runtime == "" || c.isRuntimeFromEnv {
+		return c.Runtime
+	}
+	return Runtime{Path: runtime}
+}
+
 func ConfigPath() string {
 	return filepath.Join(ConfigDir(), "config.yaml")
 }
 
 func GetConfig() Config {
 	once.Do(func() {
+		config = Config{}
 		err := parseYaml(ConfigPath(), &config)
 		if err != nil && !os.IsNotExist(err) {
 			fmt.Printf("Invalid whalebrew configuration in %s: %v\n", filepath.Join(ConfigDir(), "config.yaml"), err)
@@ -98,6 +146,16 @@ func GetConfig() Config {
 		if os.Getenv("WHALEBREW_INSTALL_PATH") != "" {
 			config.InstallPath = os.Getenv("WHALEBREW_INSTALL_PATH")
 		}
+		if locked, err := strconv.ParseBool(os.Getenv("WHALEBREW_LOCKED")); err == nil {
+			config.Locked = locked
+		}
+		if os.Getenv("WHALEBREW_RUNNER") != "" {
+			config.Runner = os.Getenv("WHALEBREW_RUNNER")
+		}
+		if os.Getenv("WHALEBREW_RUNTIME") != "" {
+			config.Runtime = Runtime{Path: os.Getenv("WHALEBREW_RUNTIME")}
+			config.isRuntimeFromEnv = true
+		}
 		if config.InstallPath == "" {
 			config.InstallPath = defaultInstallDir()
 			config.isDefaultInstallPath = true
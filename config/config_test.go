@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -118,3 +119,69 @@ func TestGetConfig(t *testing.T) {
 		})
 	})
 }
+
+func TestGetConfigLocked(t *testing.T) {
+	t.Cleanup(func() {
+		config.Reset()
+		os.RemoveAll(".test-resources")
+	})
+	t.Setenv("WHALEBREW_CONFIG_DIR", ".test-resources/whalebrew")
+	createConfigFile(t, ".test-resources/whalebrew", strings.NewReader(`locked: true`))
+
+	t.Run("When locked is not provided as an environment variable", func(t *testing.T) {
+		t.Setenv("WHALEBREW_LOCKED", "")
+		config.Reset()
+		assert.True(t, config.GetConfig().Locked)
+	})
+	t.Run("When locked is provided as an environment variable", func(t *testing.T) {
+		t.Setenv("WHALEBREW_LOCKED", "false")
+		config.Reset()
+		assert.False(t, config.GetConfig().Locked)
+	})
+}
+
+func TestGetConfigRuntime(t *testing.T) {
+	t.Cleanup(func() {
+		config.Reset()
+		os.RemoveAll(".test-resources")
+	})
+	t.Setenv("WHALEBREW_CONFIG_DIR", ".test-resources/whalebrew")
+	createConfigFile(t, ".test-resources/whalebrew", strings.NewReader("runtime:\n  path: /usr/bin/podman\n  flavour: podman\n  args: [--remote]\n"))
+
+	t.Run("When runtime is not provided as an environment variable", func(t *testing.T) {
+		t.Setenv("WHALEBREW_RUNTIME", "")
+		config.Reset()
+		expected := config.Runtime{Path: "/usr/bin/podman", Flavour: "podman", Args: []string{"--remote"}}
+		assert.Equal(t, expected, config.GetConfig().Runtime)
+		assert.Equal(t, expected, config.GetConfig().PackageRuntime(""))
+		assert.Equal(t, config.Runtime{Path: "nerdctl"}, config.GetConfig().PackageRuntime("nerdctl"))
+	})
+	t.Run("When runtime is provided as an environment variable", func(t *testing.T) {
+		t.Setenv("WHALEBREW_RUNTIME", "docker")
+		config.Reset()
+		assert.Equal(t, config.Runtime{Path: "docker"}, config.GetConfig().Runtime)
+		assert.Equal(t, config.Runtime{Path: "docker"}, config.GetConfig().PackageRuntime("nerdctl"))
+	})
+}
+
+func TestGetConfigHooks(t *testing.T) {
+	t.Cleanup(func() {
+		config.Reset()
+		os.RemoveAll(".test-resources")
+	})
+	t.Setenv("WHALEBREW_CONFIG_DIR", ".test-resources/whalebrew")
+	createConfigFile(t, ".test-resources/whalebrew", strings.NewReader("hooks:\n  continue_on_error: true\n  timeout: 30s\n"))
+	config.Reset()
+	assert.Equal(t, config.Hooks{ContinueOnError: true, Timeout: 30 * time.Second}, config.GetConfig().Hooks)
+}
+
+func TestGetConfigVerify(t *testing.T) {
+	t.Cleanup(func() {
+		config.Reset()
+		os.RemoveAll(".test-resources")
+	})
+	t.Setenv("WHALEBREW_CONFIG_DIR", ".test-resources/whalebrew")
+	createConfigFile(t, ".test-resources/whalebrew", strings.NewReader("verify:\n- registry: docker.io/whalebrew\n  keys: [whalebrew.pub]\n  on_run: true\n"))
+	config.Reset()
+	assert.Equal(t, []config.Verify{{Registry: "docker.io/whalebrew", Keys: []string{"whalebrew.pub"}, OnRun: true}}, config.GetConfig().Verify)
+}
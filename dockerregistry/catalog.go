@@ -1,13 +1,58 @@
 package dockerregistry
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+const catalogPageSize = "100"
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
 // Catalog lists repositories in a registry.
 // See https://docs.docker.com/registry/spec/api/#catalog
 type Catalog struct {
 	Repositories []string `json:"repositories"`
 }
 
-func (r *Registry) Catalog() (Catalog, error) {
-	c := Catalog{}
-	err := r.Get("/v2/_catalog", &c)
-	return c, err
+// Catalog lists all the repositories of the registry, following the pagination links
+func (r *Registry) Catalog(ctx context.Context) (Catalog, error) {
+	c := Catalog{Repositories: []string{}}
+	next := "/v2/_catalog?n=" + catalogPageSize
+	for next != "" {
+		u, err := url.Parse(next)
+		if err != nil {
+			return c, fmt.Errorf("invalid catalog link %s: %w", next, err)
+		}
+		req, err := r.NewRequest(http.MethodGet, u.Path, nil)
+		if err != nil {
+			return c, err
+		}
+		req.URL.RawQuery = u.RawQuery
+		resp, err := r.Do(req.WithContext(ctx))
+		if err != nil {
+			return c, err
+		}
+		page := Catalog{}
+		err = func() error {
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("Unexpected status %d, expecting %d", resp.StatusCode, http.StatusOK)
+			}
+			return json.NewDecoder(resp.Body).Decode(&page)
+		}()
+		if err != nil {
+			return c, err
+		}
+		c.Repositories = append(c.Repositories, page.Repositories...)
+		next = ""
+		if matches := nextLinkPattern.FindStringSubmatch(resp.Header.Get("Link")); matches != nil {
+			next = matches[1]
+		}
+	}
+	return c, nil
 }
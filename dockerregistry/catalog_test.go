@@ -0,0 +1,33 @@
+package dockerregistry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogPages(t *testing.T) {
+	r := newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v2/_catalog" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch req.URL.Query().Get("last") {
+		case "":
+			assert.Equal(t, "100", req.URL.Query().Get("n"))
+			w.Header().Set("Link", `</v2/_catalog?last=some%2Fimage&n=100>; rel="next"`)
+			fmt.Fprint(w, `{"repositories":["other/image","some/image"]}`)
+		case "some/image":
+			fmt.Fprint(w, `{"repositories":["third/image"]}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	catalog, err := r.Catalog(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other/image", "some/image", "third/image"}, catalog.Repositories)
+}
@@ -0,0 +1,144 @@
+package dockerregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"runtime"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+var _ run.ImageInspecter = &Inspecter{}
+
+// CurrentPlatform returns the platform of the images run on this machine
+func CurrentPlatform() imagev1.Platform {
+	p := imagev1.Platform{OS: "linux", Architecture: runtime.GOARCH}
+	switch runtime.GOARCH {
+	case "arm64":
+		p.Variant = "v8"
+	case "arm":
+		p.Variant = "v7"
+	}
+	return p
+}
+
+func matchesPlatform(candidate *imagev1.Platform, platform imagev1.Platform) bool {
+	if candidate == nil || candidate.OS != platform.OS || candidate.Architecture != platform.Architecture {
+		return false
+	}
+	return candidate.Variant == "" || platform.Variant == "" || candidate.Variant == platform.Variant
+}
+
+// contentMediaType returns the media type of the manifest, read from its content when the registry does not provide it
+func (m Manifest) contentMediaType() string {
+	if mediaType, _, err := mime.ParseMediaType(m.MediaType); err == nil {
+		for _, known := range manifestMediaTypes {
+			if mediaType == known {
+				return mediaType
+			}
+		}
+	}
+	content := struct {
+		MediaType string `json:"mediaType"`
+	}{}
+	json.Unmarshal(m.Content, &content)
+	return content.MediaType
+}
+
+// Blob downloads a blob of a repository, checking its content matches its digest
+func (r *Registry) Blob(ctx context.Context, name, digest string) ([]byte, error) {
+	req, err := r.NewRequest(http.MethodGet, fmt.Sprintf("/v2/%s/blobs/%s", name, digest), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status %d, expecting %d", resp.StatusCode, http.StatusOK)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if actual := fmt.Sprintf("sha256:%x", sha256.Sum256(content)); actual != digest {
+		return nil, fmt.Errorf("blob %s of %s has digest %s", digest, name, actual)
+	}
+	return content, nil
+}
+
+// ImageConfig downloads the configuration of an image without pulling its layers.
+// When the image is an index, the manifest of the given platform is used.
+func (r *Registry) ImageConfig(ctx context.Context, name, reference string, platform imagev1.Platform) (*imagev1.Image, error) {
+	m, err := r.Manifest(ctx, name, reference)
+	if err != nil {
+		return nil, err
+	}
+	switch m.contentMediaType() {
+	case MediaTypeDockerManifestList, imagev1.MediaTypeImageIndex:
+		index := imagev1.Index{}
+		if err := json.Unmarshal(m.Content, &index); err != nil {
+			return nil, fmt.Errorf("invalid index %s of %s: %w", reference, name, err)
+		}
+		found := false
+		for _, descriptor := range index.Manifests {
+			if matchesPlatform(descriptor.Platform, platform) {
+				found = true
+				m, err = r.Manifest(ctx, name, descriptor.Digest.String())
+				if err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("image %s:%s is not available for %s/%s", name, reference, platform.OS, platform.Architecture)
+		}
+	case MediaTypeDockerManifest, imagev1.MediaTypeImageManifest:
+	default:
+		return nil, fmt.Errorf("unsupported manifest media type %s for %s:%s", m.contentMediaType(), name, reference)
+	}
+
+	manifest := imagev1.Manifest{}
+	if err := json.Unmarshal(m.Content, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s of %s: %w", reference, name, err)
+	}
+	content, err := r.Blob(ctx, name, manifest.Config.Digest.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable to download the configuration of %s:%s: %w", name, reference, err)
+	}
+	image := &imagev1.Image{}
+	if err := json.Unmarshal(content, image); err != nil {
+		return nil, fmt.Errorf("invalid configuration of %s:%s: %w", name, reference, err)
+	}
+	return image, nil
+}
+
+// Inspecter inspects images reading their configuration from their registry, without pulling them
+type Inspecter struct {
+	// RegistryFor returns the registry hosting an image
+	RegistryFor func(Reference) *Registry
+	Platform    imagev1.Platform
+}
+
+// ImageInspect returns the configuration of an image as stored in its registry
+func (i *Inspecter) ImageInspect(imageName string) (*imagev1.Image, error) {
+	return i.ImageInspectContext(context.Background(), imageName)
+}
+
+// ImageInspectContext returns the configuration of an image as stored in its registry, giving up when ctx is done
+func (i *Inspecter) ImageInspectContext(ctx context.Context, imageName string) (*imagev1.Image, error) {
+	ref, err := ParseReference(imageName)
+	if err != nil {
+		return nil, err
+	}
+	return i.RegistryFor(ref).ImageConfig(ctx, ref.Path, ref.Reference(), i.Platform)
+}
@@ -0,0 +1,93 @@
+package dockerregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func digestOf(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// testImageRegistry serves some/image:latest as an index of a linux/amd64 image
+// and some/image:single as a single manifest, with the same configuration
+func testImageRegistry(t *testing.T, config []byte) *Registry {
+	manifest := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"%s","config":{"mediaType":"%s","digest":"%s","size":%d}}`, imagev1.MediaTypeImageManifest, imagev1.MediaTypeImageConfig, digestOf(config), len(config)))
+	index := []byte(fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"mediaType":"%s","digest":"sha256:attestation","size":1,"platform":{"os":"unknown","architecture":"unknown"}},{"mediaType":"%s","digest":"%s","size":%d,"platform":{"os":"linux","architecture":"amd64"}}]}`, imagev1.MediaTypeImageManifest, imagev1.MediaTypeImageManifest, digestOf(manifest), len(manifest)))
+	return newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v2/some/image/manifests/latest":
+			w.Header().Set("Content-Type", MediaTypeDockerManifestList)
+			w.Write(index)
+		case "/v2/some/image/manifests/single", "/v2/some/image/manifests/" + digestOf(manifest):
+			// some registries do not provide the content type
+			w.Write(manifest)
+		case "/v2/some/image/blobs/" + digestOf(config):
+			w.Write(config)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestImageConfig(t *testing.T) {
+	config, err := json.Marshal(imagev1.Image{Config: imagev1.ImageConfig{
+		Entrypoint: []string{"jq"},
+		Labels:     map[string]string{"io.whalebrew.config.ports": `["8080:8080"]`},
+	}})
+	require.NoError(t, err)
+	r := testImageRegistry(t, config)
+	amd64 := imagev1.Platform{OS: "linux", Architecture: "amd64"}
+
+	image, err := r.ImageConfig(context.Background(), "some/image", "latest", amd64)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jq"}, image.Config.Entrypoint)
+	assert.Equal(t, `["8080:8080"]`, image.Config.Labels["io.whalebrew.config.ports"])
+
+	image, err = r.ImageConfig(context.Background(), "some/image", "single", imagev1.Platform{OS: "linux", Architecture: "arm64"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jq"}, image.Config.Entrypoint)
+
+	_, err = r.ImageConfig(context.Background(), "some/image", "latest", imagev1.Platform{OS: "linux", Architecture: "arm64"})
+	assert.Error(t, err)
+	_, err = r.ImageConfig(context.Background(), "other/image", "latest", amd64)
+	assert.Error(t, err)
+
+	i := &Inspecter{
+		RegistryFor: func(ref Reference) *Registry {
+			assert.Equal(t, "localhost:5000", ref.Domain)
+			return r
+		},
+		Platform: amd64,
+	}
+	image, err = i.ImageInspect("localhost:5000/some/image")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jq"}, image.Config.Entrypoint)
+}
+
+func TestBlobChecksDigest(t *testing.T) {
+	r := newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("tampered"))
+	}))
+	_, err := r.Blob(context.Background(), "some/image", digestOf([]byte("content")))
+	assert.Error(t, err)
+	content, err := r.Blob(context.Background(), "some/image", digestOf([]byte("tampered")))
+	require.NoError(t, err)
+	assert.Equal(t, "tampered", string(content))
+}
+
+func TestMatchesPlatform(t *testing.T) {
+	assert.True(t, matchesPlatform(&imagev1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, imagev1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}))
+	assert.True(t, matchesPlatform(&imagev1.Platform{OS: "linux", Architecture: "arm64"}, imagev1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}))
+	assert.False(t, matchesPlatform(&imagev1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}, imagev1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}))
+	assert.False(t, matchesPlatform(&imagev1.Platform{OS: "windows", Architecture: "amd64"}, imagev1.Platform{OS: "linux", Architecture: "amd64"}))
+	assert.False(t, matchesPlatform(nil, imagev1.Platform{OS: "linux", Architecture: "amd64"}))
+}
@@ -0,0 +1,95 @@
+package dockerregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	contentDigestHeader = "Docker-Content-Digest"
+)
+
+var manifestMediaTypes = []string{
+	MediaTypeDockerManifestList,
+	MediaTypeDockerManifest,
+	imagev1.MediaTypeImageIndex,
+	imagev1.MediaTypeImageManifest,
+}
+
+// Manifest is a manifest as served by the registry.
+// See https://docs.docker.com/registry/spec/api/#manifest
+type Manifest struct {
+	MediaType string
+	Digest    string
+	Content   []byte
+}
+
+func (r *Registry) newManifestRequest(method, name, reference string) (*http.Request, error) {
+	req, err := r.NewRequest(method, fmt.Sprintf("/v2/%s/manifests/%s", name, reference), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	return req, nil
+}
+
+// ManifestDigest resolves the digest of the manifest of an image without downloading it when the registry allows it
+func (r *Registry) ManifestDigest(name, reference string) (string, error) {
+	req, err := r.newManifestRequest(http.MethodHead, name, reference)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unexpected status %d, expecting %d", resp.StatusCode, http.StatusOK)
+	}
+	if digest := resp.Header.Get(contentDigestHeader); digest != "" {
+		return digest, nil
+	}
+	// Some registries do not provide the digest on HEAD requests, compute it from the content
+	m, err := r.Manifest(context.Background(), name, reference)
+	if err != nil {
+		return "", err
+	}
+	return m.Digest, nil
+}
+
+// Manifest downloads the manifest of an image
+func (r *Registry) Manifest(ctx context.Context, name, reference string) (Manifest, error) {
+	m := Manifest{}
+	req, err := r.newManifestRequest(http.MethodGet, name, reference)
+	if err != nil {
+		return m, err
+	}
+	resp, err := r.Do(req.WithContext(ctx))
+	if err != nil {
+		return m, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return m, fmt.Errorf("Unexpected status %d, expecting %d", resp.StatusCode, http.StatusOK)
+	}
+	m.Content, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return m, err
+	}
+	m.MediaType = resp.Header.Get("Content-Type")
+	m.Digest = resp.Header.Get(contentDigestHeader)
+	if m.Digest == "" {
+		m.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256(m.Content))
+	}
+	return m, nil
+}
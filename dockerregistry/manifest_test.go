@@ -0,0 +1,72 @@
+package dockerregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`
+
+func newTestRegistry(t *testing.T, handler http.Handler) *Registry {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &Registry{
+		Host:    strings.TrimPrefix(server.URL, "http://"),
+		UseHTTP: true,
+	}
+}
+
+func TestManifest(t *testing.T) {
+	withDigestHeader := true
+	r := newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v2/some/image/manifests/latest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Contains(t, req.Header.Get("Accept"), MediaTypeDockerManifestList)
+		assert.Contains(t, req.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		if withDigestHeader {
+			w.Header().Set("Docker-Content-Digest", "sha256:1234")
+		}
+		if req.Method == http.MethodGet {
+			fmt.Fprint(w, testManifest)
+		}
+	}))
+
+	t.Run("when the registry provides the digest", func(t *testing.T) {
+		withDigestHeader = true
+		digest, err := r.ManifestDigest("some/image", "latest")
+		require.NoError(t, err)
+		assert.Equal(t, "sha256:1234", digest)
+
+		m, err := r.Manifest(context.Background(), "some/image", "latest")
+		require.NoError(t, err)
+		assert.Equal(t, "sha256:1234", m.Digest)
+		assert.Equal(t, "application/vnd.oci.image.manifest.v1+json", m.MediaType)
+		assert.Equal(t, testManifest, string(m.Content))
+	})
+
+	t.Run("when the registry does not provide the digest", func(t *testing.T) {
+		withDigestHeader = false
+		digest, err := r.ManifestDigest("some/image", "latest")
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(testManifest))), digest)
+	})
+
+	t.Run("when the image does not exist", func(t *testing.T) {
+		_, err := r.ManifestDigest("other/image", "latest")
+		assert.Error(t, err)
+		_, err = r.Manifest(context.Background(), "other/image", "latest")
+		assert.Error(t, err)
+	})
+}
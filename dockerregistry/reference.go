@@ -0,0 +1,112 @@
+package dockerregistry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	dockerHubDomain = "docker.io"
+	defaultTag      = "latest"
+)
+
+// dockerHubAliases are the other domains docker hub images are named with
+var dockerHubAliases = []string{"index.docker.io", "registry-1.docker.io"}
+
+// Reference is a parsed image name like registry.example.com/owner/image:tag
+type Reference struct {
+	// Domain is the registry hosting the image, docker.io for docker hub
+	Domain string
+	// Path is the repository name in the registry
+	Path   string
+	Tag    string
+	Digest string
+}
+
+// ParseReference parses an image name as provided to docker commands.
+// Images without registry are considered to be hosted on docker hub, named docker.io whatever the alias used,
+// and images without tag nor digest are considered to use the latest tag.
+func ParseReference(image string) (Reference, error) {
+	ref := Reference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if ref.Digest == "" {
+			return ref, fmt.Errorf("invalid image reference %s: empty digest", image)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+		if ref.Tag == "" {
+			return ref, fmt.Errorf("invalid image reference %s: empty tag", image)
+		}
+	}
+	ref.Domain = dockerHubDomain
+	if i := strings.Index(name, "/"); i >= 0 {
+		domain := name[:i]
+		if strings.ContainsAny(domain, ".:") || domain == "localhost" {
+			ref.Domain, name = domain, name[i+1:]
+		}
+		for _, alias := range dockerHubAliases {
+			if ref.Domain == alias {
+				ref.Domain = dockerHubDomain
+			}
+		}
+	}
+	if name == "" {
+		return ref, fmt.Errorf("invalid image reference %s: empty name", image)
+	}
+	if ref.Domain == dockerHubDomain && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Path = name
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+	return ref, nil
+}
+
+// Reference returns the tag or digest to use when fetching the image manifest.
+// The digest has precedence over the tag.
+func (r Reference) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Name returns the fully qualified repository name, without tag nor digest
+func (r Reference) Name() string {
+	return r.Domain + "/" + r.Path
+}
+
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// IsDockerHub returns whether the image is hosted on docker hub
+func (r Reference) IsDockerHub() bool {
+	return r.Domain == dockerHubDomain
+}
+
+// MatchingDigest returns the digest of the repository digests (as shown in docker image inspect RepoDigests)
+// that belongs to the same repository as the reference.
+func MatchingDigest(ref Reference, repoDigests []string) (string, bool) {
+	for _, repoDigest := range repoDigests {
+		candidate, err := ParseReference(repoDigest)
+		if err != nil || candidate.Digest == "" {
+			continue
+		}
+		if candidate.Name() == ref.Name() {
+			return candidate.Digest, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,58 @@
+package dockerregistry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	for image, expected := range map[string]Reference{
+		"alpine":                               {Domain: "docker.io", Path: "library/alpine", Tag: "latest"},
+		"whalebrew/jq":                         {Domain: "docker.io", Path: "whalebrew/jq", Tag: "latest"},
+		"whalebrew/jq:1.6":                     {Domain: "docker.io", Path: "whalebrew/jq", Tag: "1.6"},
+		"whalebrew/jq@sha256:1234":             {Domain: "docker.io", Path: "whalebrew/jq", Digest: "sha256:1234"},
+		"whalebrew/jq:1.6@sha256:1234":         {Domain: "docker.io", Path: "whalebrew/jq", Tag: "1.6", Digest: "sha256:1234"},
+		"localhost:5000/some/image":            {Domain: "localhost:5000", Path: "some/image", Tag: "latest"},
+		"localhost/some/image:v1":              {Domain: "localhost", Path: "some/image", Tag: "v1"},
+		"quay.io/some/registry/example:latest": {Domain: "quay.io", Path: "some/registry/example", Tag: "latest"},
+		"index.docker.io/whalebrew/jq":         {Domain: "docker.io", Path: "whalebrew/jq", Tag: "latest"},
+		"registry-1.docker.io/library/alpine":  {Domain: "docker.io", Path: "library/alpine", Tag: "latest"},
+		"index.docker.io/alpine:3":             {Domain: "docker.io", Path: "library/alpine", Tag: "3"},
+	} {
+		t.Run(image, func(t *testing.T) {
+			ref, err := ParseReference(image)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, ref)
+		})
+	}
+	for _, image := range []string{"", "whalebrew/jq:", "whalebrew/jq@", "localhost:5000/"} {
+		t.Run("invalid "+image, func(t *testing.T) {
+			_, err := ParseReference(image)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReference(t *testing.T) {
+	ref := Reference{Domain: "docker.io", Path: "whalebrew/jq", Tag: "1.6"}
+	assert.Equal(t, "1.6", ref.Reference())
+	assert.Equal(t, "docker.io/whalebrew/jq:1.6", ref.String())
+	assert.True(t, ref.IsDockerHub())
+	ref.Digest = "sha256:1234"
+	assert.Equal(t, "sha256:1234", ref.Reference())
+	assert.Equal(t, "docker.io/whalebrew/jq:1.6@sha256:1234", ref.String())
+}
+
+func TestMatchingDigest(t *testing.T) {
+	ref, err := ParseReference("whalebrew/jq:1.6")
+	assert.NoError(t, err)
+	digest, ok := MatchingDigest(ref, []string{"localhost:5000/whalebrew/jq@sha256:abcd", "docker.io/whalebrew/jq@sha256:1234"})
+	assert.True(t, ok)
+	assert.Equal(t, "sha256:1234", digest)
+	digest, ok = MatchingDigest(ref, []string{"whalebrew/jq@sha256:5678"})
+	assert.True(t, ok)
+	assert.Equal(t, "sha256:5678", digest)
+	_, ok = MatchingDigest(ref, []string{"localhost:5000/whalebrew/jq@sha256:abcd"})
+	assert.False(t, ok)
+}
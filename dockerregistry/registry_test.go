@@ -1,6 +1,7 @@
 package dockerregistry
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -68,7 +69,7 @@ func TestRegistryCatalogWithoutAuthentication(t *testing.T) {
 			Host:    "localhost:5000",
 			UseHTTP: true,
 		}
-		cat, err := r.Catalog()
+		cat, err := r.Catalog(context.Background())
 		assert.NoError(t, err)
 		assert.Equal(t, len(cat.Repositories), 1)
 	})
@@ -111,7 +112,7 @@ func TestRegistryCatalogWithoutAuthentication(t *testing.T) {
 			Host:    "localhost:5000",
 			UseHTTP: true,
 		}
-		cat, err := r.Catalog()
+		cat, err := r.Catalog(context.Background())
 		assert.NoError(t, err)
 		assert.Equal(t, len(cat.Repositories), 1)
 	})
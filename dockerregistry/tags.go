@@ -0,0 +1,33 @@
+package dockerregistry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TagList lists the tags of a repository.
+// See https://docs.docker.com/registry/spec/api/#listing-image-tags
+type TagList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// Tags lists the tags of a repository
+func (r *Registry) Tags(ctx context.Context, name string) (TagList, error) {
+	t := TagList{}
+	req, err := r.NewRequest(http.MethodGet, fmt.Sprintf("/v2/%s/tags/list", name), nil)
+	if err != nil {
+		return t, err
+	}
+	resp, err := r.Do(req.WithContext(ctx))
+	if err != nil {
+		return t, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return t, fmt.Errorf("Unexpected status %d, expecting %d", resp.StatusCode, http.StatusOK)
+	}
+	return t, json.NewDecoder(resp.Body).Decode(&t)
+}
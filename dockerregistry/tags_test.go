@@ -0,0 +1,27 @@
+package dockerregistry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	r := newTestRegistry(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v2/some/image/tags/list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"name":"some/image","tags":["latest","1.0"]}`)
+	}))
+	tags, err := r.Tags(context.Background(), "some/image")
+	require.NoError(t, err)
+	assert.Equal(t, TagList{Name: "some/image", Tags: []string{"latest", "1.0"}}, tags)
+
+	_, err = r.Tags(context.Background(), "other/image")
+	assert.Error(t, err)
+}
@@ -0,0 +1,282 @@
+package doctor
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/whalebrew/whalebrew/hooks"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+	"github.com/whalebrew/whalebrew/version"
+	"gopkg.in/yaml.v3"
+)
+
+// packageFiles lists the names of the packages installed in installPath
+func packageFiles(installPath string) ([]string, error) {
+	files, err := ioutil.ReadDir(installPath)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, file := range files {
+		isPackage, err := packages.IsPackage(filepath.Join(installPath, file.Name()))
+		if err == nil && isPackage {
+			names = append(names, file.Name())
+		}
+	}
+	return names, nil
+}
+
+// looksLikePackage reports whether the file at path is a package definition, whatever its shebang
+func looksLikePackage(path string) bool {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(string(content), "#!") {
+		i := strings.Index(string(content), "\n")
+		if i < 0 {
+			return false
+		}
+		content = content[i+1:]
+	}
+	definition := struct {
+		Image string `yaml:"image"`
+	}{}
+	return yaml.Unmarshal(content, &definition) == nil && definition.Image != ""
+}
+
+// firstLine returns the first line of the file at path
+func firstLine(path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.SplitN(string(content), "\n", 2)[0]
+}
+
+// InstallPathCheck checks the install path exists and is in $PATH
+type InstallPathCheck struct {
+	InstallPath string
+	// Path is the value of the $PATH environment variable
+	Path string
+}
+
+func (c InstallPathCheck) Name() string {
+	return "install-path"
+}
+
+func (c InstallPathCheck) Run() []Finding {
+	stat, err := os.Stat(c.InstallPath)
+	if err != nil {
+		return []Finding{failure("%v", err)}
+	}
+	if !stat.IsDir() {
+		return []Finding{failure("%s is not a directory", c.InstallPath)}
+	}
+	for _, dir := range filepath.SplitList(c.Path) {
+		if filepath.Clean(dir) == filepath.Clean(c.InstallPath) {
+			return []Finding{ok("%s is in your $PATH", c.InstallPath)}
+		}
+	}
+	return []Finding{warning("%s is not in your $PATH, add it to run packages by their name", c.InstallPath)}
+}
+
+// CommandsCheck checks each installed package is the command found in $PATH
+type CommandsCheck struct {
+	InstallPath string
+}
+
+func (c CommandsCheck) Name() string {
+	return "commands"
+}
+
+func (c CommandsCheck) Run() []Finding {
+	names, err := packageFiles(c.InstallPath)
+	if err != nil {
+		return []Finding{failure("unable to list packages: %v", err)}
+	}
+	findings := []Finding{}
+	for _, name := range names {
+		installPath := filepath.Clean(filepath.Join(c.InstallPath, name))
+		cmdPath, err := exec.LookPath(name)
+		if err != nil {
+			findings = append(findings, warning("%s is not found in your $PATH", name))
+		} else if filepath.Clean(cmdPath) != installPath {
+			findings = append(findings, warning("%s resolves to %s instead of %s", name, cmdPath, installPath))
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, ok("%d packages resolve to their installed command", len(names)))
+	}
+	return findings
+}
+
+// PackagesCheck checks installed packages can be loaded
+type PackagesCheck struct {
+	InstallPath string
+	Loader      packages.Loader
+}
+
+func (c PackagesCheck) Name() string {
+	return "packages"
+}
+
+func (c PackagesCheck) Run() []Finding {
+	names, err := packageFiles(c.InstallPath)
+	if err != nil {
+		return []Finding{failure("unable to list packages: %v", err)}
+	}
+	findings := []Finding{}
+	for _, name := range names {
+		pkg, err := c.Loader.LoadPackageFromPath(filepath.Join(c.InstallPath, name))
+		// incompatible versions are reported by the versions check
+		if err != nil && (pkg == nil || pkg.RequiredVersion == "" || version.CheckCompatible(pkg.RequiredVersion) == nil) {
+			findings = append(findings, failure("%s is invalid: %v", name, err))
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, ok("%d packages are valid", len(names)))
+	}
+	return findings
+}
+
+// ShebangsCheck checks packages can be run by their shebang:
+// whalebrew must be found in $PATH and package definitions must start with #!/usr/bin/env whalebrew
+type ShebangsCheck struct {
+	InstallPath string
+}
+
+func (c ShebangsCheck) Name() string {
+	return "shebangs"
+}
+
+func (c ShebangsCheck) Run() []Finding {
+	files, err := ioutil.ReadDir(c.InstallPath)
+	if err != nil {
+		return []Finding{failure("unable to list packages: %v", err)}
+	}
+	findings := []Finding{}
+	whalebrew, err := exec.LookPath("whalebrew")
+	if err != nil {
+		findings = append(findings, failure("whalebrew is not found in your $PATH, packages can not run"))
+	}
+	for _, file := range files {
+		path := filepath.Join(c.InstallPath, file.Name())
+		if file.IsDir() || file.Mode()&0111 == 0 {
+			continue
+		}
+		if isPackage, err := packages.IsPackage(path); err == nil && isPackage {
+			continue
+		}
+		if looksLikePackage(path) {
+			findings = append(findings, failure("%s looks like a package but starts with %q instead of #!/usr/bin/env whalebrew", file.Name(), firstLine(path)))
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, ok("packages run with %s", whalebrew))
+	}
+	return findings
+}
+
+// VersionsCheck checks installed packages support the current whalebrew version
+type VersionsCheck struct {
+	InstallPath string
+	Loader      packages.Loader
+}
+
+func (c VersionsCheck) Name() string {
+	return "versions"
+}
+
+func (c VersionsCheck) Run() []Finding {
+	names, err := packageFiles(c.InstallPath)
+	if err != nil {
+		return []Finding{failure("unable to list packages: %v", err)}
+	}
+	findings := []Finding{}
+	for _, name := range names {
+		pkg, _ := c.Loader.LoadPackageFromPath(filepath.Join(c.InstallPath, name))
+		if pkg == nil || pkg.RequiredVersion == "" {
+			continue
+		}
+		if err := version.CheckCompatible(pkg.RequiredVersion); err != nil {
+			findings = append(findings, failure("%s: %v", name, err))
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, ok("packages are compatible with whalebrew %s", version.Version))
+	}
+	return findings
+}
+
+// RuntimeCheck checks the container engine answers
+type RuntimeCheck struct {
+	Engine func() (run.Pinger, error)
+}
+
+func (c RuntimeCheck) Name() string {
+	return "runtime"
+}
+
+func (c RuntimeCheck) Run() []Finding {
+	engine, err := c.Engine()
+	if err != nil {
+		return []Finding{failure("%v", err)}
+	}
+	if err := engine.Ping(); err != nil {
+		return []Finding{failure("%v", err)}
+	}
+	return []Finding{ok("the container engine answers")}
+}
+
+// HooksCheck checks the hooks in Dir can be run
+type HooksCheck struct {
+	Dir string
+}
+
+func (c HooksCheck) Name() string {
+	return "hooks"
+}
+
+func (c HooksCheck) Run() []Finding {
+	files, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return []Finding{ok("no hooks installed in %s", c.Dir)}
+	}
+	if err != nil {
+		return []Finding{failure("unable to list hooks: %v", err)}
+	}
+	findings := []Finding{}
+	checked := map[string]bool{}
+	count := 0
+	for _, file := range files {
+		hook := strings.TrimSuffix(file.Name(), ".d")
+		if !hooks.IsKnown(hook) {
+			findings = append(findings, warning("%s is not a known hook, expecting one of %v", filepath.Join(c.Dir, file.Name()), hooks.Known))
+			continue
+		}
+		if checked[hook] {
+			continue
+		}
+		checked[hook] = true
+		scripts, err := hooks.ScriptsIn(c.Dir, hook)
+		if err != nil {
+			findings = append(findings, failure("%v", err))
+			continue
+		}
+		for _, script := range scripts {
+			if !script.Executable {
+				findings = append(findings, failure("%s is not executable", script.Path))
+			}
+		}
+		count += len(scripts)
+	}
+	if len(findings) == 0 {
+		findings = append(findings, ok("%d hooks are executable", count))
+	}
+	return findings
+}
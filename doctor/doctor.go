@@ -0,0 +1,97 @@
+package doctor
+
+import (
+	"fmt"
+	"io"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarning Status = "warning"
+	StatusError   Status = "error"
+)
+
+var statusIcons = map[Status]string{
+	StatusOK:      "✅",
+	StatusWarning: "❗️",
+	StatusError:   "❌",
+}
+
+// Finding is something a check noticed
+type Finding struct {
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Check inspects one aspect of the whalebrew environment
+type Check interface {
+	Name() string
+	Run() []Finding
+}
+
+// CheckReport holds the findings of a check
+type CheckReport struct {
+	Name     string    `json:"name"`
+	Status   Status    `json:"status"`
+	Findings []Finding `json:"findings"`
+}
+
+// Report holds the findings of all the checks run
+type Report struct {
+	OK     bool          `json:"ok"`
+	Checks []CheckReport `json:"checks"`
+}
+
+// Run runs the checks in order.
+// The report is OK unless one of the checks found an error.
+func Run(checks ...Check) Report {
+	report := Report{OK: true, Checks: []CheckReport{}}
+	for _, check := range checks {
+		r := CheckReport{Name: check.Name(), Status: StatusOK, Findings: check.Run()}
+		for _, finding := range r.Findings {
+			r.Status = worst(r.Status, finding.Status)
+		}
+		if r.Status == StatusError {
+			report.OK = false
+		}
+		report.Checks = append(report.Checks, r)
+	}
+	return report
+}
+
+func worst(a, b Status) Status {
+	if a == StatusError || b == StatusError {
+		return StatusError
+	}
+	if a == StatusWarning || b == StatusWarning {
+		return StatusWarning
+	}
+	return StatusOK
+}
+
+// WriteText writes a human readable version of the report
+func (r Report) WriteText(w io.Writer) error {
+	for _, check := range r.Checks {
+		for _, finding := range check.Findings {
+			if _, err := fmt.Fprintf(w, "%s  %s: %s\n", statusIcons[finding.Status], check.Name, finding.Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func ok(format string, args ...interface{}) Finding {
+	return Finding{Status: StatusOK, Message: fmt.Sprintf(format, args...)}
+}
+
+func warning(format string, args ...interface{}) Finding {
+	return Finding{Status: StatusWarning, Message: fmt.Sprintf(format, args...)}
+}
+
+func failure(format string, args ...interface{}) Finding {
+	return Finding{Status: StatusError, Message: fmt.Sprintf(format, args...)}
+}
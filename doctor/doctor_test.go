@@ -0,0 +1,160 @@
+package doctor_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/doctor"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+type testCheck struct {
+	name     string
+	findings []doctor.Finding
+}
+
+func (c testCheck) Name() string          { return c.name }
+func (c testCheck) Run() []doctor.Finding { return c.findings }
+
+type testPinger struct {
+	err error
+}
+
+func (p testPinger) Ping() error { return p.err }
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), mode))
+}
+
+func TestRun(t *testing.T) {
+	report := doctor.Run(
+		testCheck{"first", []doctor.Finding{{Status: doctor.StatusOK, Message: "fine"}}},
+		testCheck{"second", []doctor.Finding{{Status: doctor.StatusWarning, Message: "hmm"}, {Status: doctor.StatusOK, Message: "fine"}}},
+	)
+	assert.True(t, report.OK)
+	assert.Equal(t, doctor.StatusOK, report.Checks[0].Status)
+	assert.Equal(t, doctor.StatusWarning, report.Checks[1].Status)
+
+	report = doctor.Run(
+		testCheck{"first", []doctor.Finding{{Status: doctor.StatusError, Message: "broken"}, {Status: doctor.StatusWarning, Message: "hmm"}}},
+	)
+	assert.False(t, report.OK)
+	assert.Equal(t, doctor.StatusError, report.Checks[0].Status)
+
+	b := &bytes.Buffer{}
+	require.NoError(t, report.WriteText(b))
+	assert.Equal(t, "❌  first: broken\n❗️  first: hmm\n", b.String())
+}
+
+func TestInstallPathCheck(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, doctor.StatusOK, doctor.Run(doctor.InstallPathCheck{InstallPath: dir, Path: "/bin" + string(os.PathListSeparator) + dir + "/"}).Checks[0].Status)
+	assert.Equal(t, doctor.StatusWarning, doctor.Run(doctor.InstallPathCheck{InstallPath: dir, Path: "/bin"}).Checks[0].Status)
+	assert.Equal(t, doctor.StatusError, doctor.Run(doctor.InstallPathCheck{InstallPath: filepath.Join(dir, "missing"), Path: "/bin"}).Checks[0].Status)
+	writeFile(t, filepath.Join(dir, "file"), "", 0644)
+	assert.Equal(t, doctor.StatusError, doctor.Run(doctor.InstallPathCheck{InstallPath: filepath.Join(dir, "file"), Path: "/bin"}).Checks[0].Status)
+}
+
+func TestPackageChecks(t *testing.T) {
+	installPath, shadowing := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(installPath, "jq"), "#!/usr/bin/env whalebrew\nimage: whalebrew/jq\n", 0755)
+	writeFile(t, filepath.Join(installPath, "not-a-package"), "#!/bin/sh\n", 0755)
+	t.Setenv("PATH", installPath)
+
+	report := doctor.Run(
+		doctor.CommandsCheck{InstallPath: installPath},
+		doctor.PackagesCheck{InstallPath: installPath, Loader: packages.DefaultLoader},
+		doctor.VersionsCheck{InstallPath: installPath, Loader: packages.DefaultLoader},
+	)
+	assert.True(t, report.OK)
+	for _, check := range report.Checks {
+		assert.Equal(t, doctor.StatusOK, check.Status, check.Name)
+	}
+
+	writeFile(t, filepath.Join(installPath, "aws"), "#!/usr/bin/env whalebrew\nimage: whalebrew/awscli\nrequired_version: \"<0.1.0\"\n", 0755)
+	writeFile(t, filepath.Join(installPath, "broken"), "#!/usr/bin/env whalebrew\nimage: [\n", 0755)
+	writeFile(t, filepath.Join(shadowing, "jq"), "#!/bin/sh\n", 0755)
+	t.Setenv("PATH", shadowing+string(os.PathListSeparator)+installPath)
+
+	report = doctor.Run(
+		doctor.CommandsCheck{InstallPath: installPath},
+		doctor.PackagesCheck{InstallPath: installPath, Loader: packages.DefaultLoader},
+		doctor.VersionsCheck{InstallPath: installPath, Loader: packages.DefaultLoader},
+	)
+	assert.False(t, report.OK)
+	assert.Equal(t, []doctor.Finding{
+		{Status: doctor.StatusWarning, Message: "jq resolves to " + filepath.Join(shadowing, "jq") + " instead of " + filepath.Join(installPath, "jq")},
+	}, report.Checks[0].Findings)
+	require.Len(t, report.Checks[1].Findings, 1)
+	assert.Equal(t, doctor.StatusError, report.Checks[1].Status)
+	assert.Contains(t, report.Checks[1].Findings[0].Message, "broken is invalid")
+	require.Len(t, report.Checks[2].Findings, 1)
+	assert.Equal(t, doctor.StatusError, report.Checks[2].Status)
+	assert.Contains(t, report.Checks[2].Findings[0].Message, "aws: ")
+}
+
+func TestShebangsCheck(t *testing.T) {
+	installPath, bin := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(installPath, "jq"), "#!/usr/bin/env whalebrew\nimage: whalebrew/jq\n", 0755)
+	writeFile(t, filepath.Join(installPath, "script"), "#!/bin/sh\necho hello\n", 0755)
+	writeFile(t, filepath.Join(installPath, "definition.yaml"), "image: whalebrew/jq\n", 0644)
+	writeFile(t, filepath.Join(bin, "whalebrew"), "#!/bin/sh\n", 0755)
+	t.Setenv("PATH", bin)
+
+	report := doctor.Run(doctor.ShebangsCheck{InstallPath: installPath})
+	assert.Equal(t, []doctor.Finding{{Status: doctor.StatusOK, Message: "packages run with " + filepath.Join(bin, "whalebrew")}}, report.Checks[0].Findings)
+
+	writeFile(t, filepath.Join(installPath, "aws"), "#!/usr/bin/env whalebrw\nimage: whalebrew/awscli\n", 0755)
+	writeFile(t, filepath.Join(installPath, "wget"), "image: whalebrew/wget\n", 0755)
+	t.Setenv("PATH", installPath)
+	report = doctor.Run(doctor.ShebangsCheck{InstallPath: installPath})
+	assert.False(t, report.OK)
+	assert.Equal(t, []doctor.Finding{
+		{Status: doctor.StatusError, Message: "whalebrew is not found in your $PATH, packages can not run"},
+		{Status: doctor.StatusError, Message: `aws looks like a package but starts with "#!/usr/bin/env whalebrw" instead of #!/usr/bin/env whalebrew`},
+		{Status: doctor.StatusError, Message: `wget looks like a package but starts with "image: whalebrew/wget" instead of #!/usr/bin/env whalebrew`},
+	}, report.Checks[0].Findings)
+}
+
+func TestRuntimeCheck(t *testing.T) {
+	check := func(pinger run.Pinger, err error) doctor.Status {
+		return doctor.Run(doctor.RuntimeCheck{Engine: func() (run.Pinger, error) { return pinger, err }}).Checks[0].Status
+	}
+	assert.Equal(t, doctor.StatusOK, check(testPinger{}, nil))
+	assert.Equal(t, doctor.StatusError, check(testPinger{err: errors.New("not running")}, nil))
+	assert.Equal(t, doctor.StatusError, check(nil, errors.New("not found")))
+}
+
+func TestHooksCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hooks")
+	assert.Equal(t, doctor.StatusOK, doctor.Run(doctor.HooksCheck{Dir: dir}).Checks[0].Status)
+
+	require.NoError(t, os.Mkdir(dir, 0755))
+	writeFile(t, filepath.Join(dir, "pre-install"), "#!/bin/sh\n", 0755)
+	assert.Equal(t, doctor.StatusOK, doctor.Run(doctor.HooksCheck{Dir: dir}).Checks[0].Status)
+
+	writeFile(t, filepath.Join(dir, "pre-instal"), "#!/bin/sh\n", 0755)
+	assert.Equal(t, doctor.StatusWarning, doctor.Run(doctor.HooksCheck{Dir: dir}).Checks[0].Status)
+
+	writeFile(t, filepath.Join(dir, "post-install"), "#!/bin/sh\n", 0644)
+	report := doctor.Run(doctor.HooksCheck{Dir: dir})
+	assert.Equal(t, doctor.StatusError, report.Checks[0].Status)
+	assert.Contains(t, report.Checks[0].Findings[0].Message, "post-install is not executable")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "pre-instal")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "post-install")))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "pre-install.d"), 0755))
+	writeFile(t, filepath.Join(dir, "pre-install.d", "10-team"), "#!/bin/sh\n", 0755)
+	assert.Equal(t, doctor.StatusOK, doctor.Run(doctor.HooksCheck{Dir: dir}).Checks[0].Status)
+	writeFile(t, filepath.Join(dir, "pre-install.d", "20-personal"), "#!/bin/sh\n", 0644)
+	report = doctor.Run(doctor.HooksCheck{Dir: dir})
+	assert.Equal(t, doctor.StatusError, report.Checks[0].Status)
+	assert.Contains(t, report.Checks[0].Findings[0].Message, "20-personal is not executable")
+}
@@ -1,18 +1,102 @@
 package hooks
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+	"github.com/whalebrew/whalebrew/version"
 )
 
+// Known lists the hooks whalebrew runs
+var Known = []string{"pre-install", "post-install", "pre-uninstall", "post-uninstall", "pre-run", "post-run"}
+
+// IsKnown tells whether whalebrew runs the hook
+func IsKnown(hook string) bool {
+	for _, known := range Known {
+		if known == hook {
+			return true
+		}
+	}
+	return false
+}
+
+// Script is an executable run for a hook
+type Script struct {
+	Hook       string `json:"hook" yaml:"hook"`
+	Path       string `json:"path" yaml:"path"`
+	Executable bool   `json:"executable" yaml:"executable"`
+}
+
+// Payload describes what a hook runs for. It is written as JSON on the standard input of the scripts.
+type Payload struct {
+	Hook        string                   `json:"hook"`
+	Package     *packages.Package        `json:"package,omitempty"`
+	Previous    *packages.Package        `json:"previous,omitempty"`
+	Permissions *packages.PermissionDiff `json:"permissions,omitempty"`
+	// Execution is how the package is run, for run hooks
+	Execution *run.Execution `json:"execution,omitempty"`
+	// ExitStatus is the exit status of the package, once it ran
+	ExitStatus *int `json:"exit_status,omitempty"`
+	// Duration is how long the package ran, in seconds
+	Duration float64 `json:"duration_seconds,omitempty"`
+	// Error tells why the package could not run
+	Error       string `json:"error,omitempty"`
+	InstallPath string `json:"install_path"`
+	Version     string `json:"whalebrew_version"`
+}
+
+// InstallPayload describes the installation of pkg, replacing previous if not nil
+func InstallPayload(pkg, previous *packages.Package) Payload {
+	diff := pkg.PermissionDiff(previous)
+	return Payload{Package: pkg, Previous: previous, Permissions: &diff}
+}
+
+// Env returns the WHALEBREW_* environment variables describing the payload
+func (p Payload) Env() []string {
+	env := []string{
+		"WHALEBREW_HOOK=" + p.Hook,
+		"WHALEBREW_INSTALL_PATH=" + p.InstallPath,
+		"WHALEBREW_VERSION=" + p.Version,
+	}
+	if p.Package != nil {
+		env = append(env,
+			"WHALEBREW_PACKAGE_NAME="+p.Package.Name,
+			"WHALEBREW_PACKAGE_IMAGE="+p.Package.Image,
+		)
+	}
+	if p.Previous != nil {
+		env = append(env, "WHALEBREW_PREVIOUS_IMAGE="+p.Previous.Image)
+	}
+	if p.ExitStatus != nil {
+		env = append(env,
+			"WHALEBREW_EXIT_STATUS="+strconv.Itoa(*p.ExitStatus),
+			"WHALEBREW_DURATION="+strconv.FormatFloat(p.Duration, 'f', 3, 64),
+		)
+	}
+	return env
+}
+
 type stater interface {
 	Stat(string) (os.FileInfo, error)
 }
 
+type dirReader interface {
+	ReadDir(string) ([]os.DirEntry, error)
+}
+
 type runner interface {
 	Run(string, ...string) error
 }
@@ -28,14 +112,38 @@ func (osStater) Stat(path string) (os.FileInfo, error) {
 	return os.Stat(path)
 }
 
-type execRunner struct{}
+type osDirReader struct{}
+
+func (osDirReader) ReadDir(path string) ([]os.DirEntry, error) {
+	return os.ReadDir(path)
+}
+
+type execRunner struct {
+	// Timeout kills the command when it runs for longer, when not zero
+	Timeout time.Duration
+	// Stdin is written on the standard input of the command
+	Stdin []byte
+	// Env is added to the environment of the command
+	Env []string
+}
 
-func (execRunner) Run(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
+func (r execRunner) Run(name string, args ...string) error {
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	return cmd.Run()
+	cmd.Stdin = bytes.NewReader(r.Stdin)
+	cmd.Env = append(os.Environ(), r.Env...)
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", r.Timeout)
+	}
+	return err
 }
 
 type osDirGetChanger struct{}
@@ -47,8 +155,69 @@ func (osDirGetChanger) Chdir(path string) error {
 	return os.Chdir(path)
 }
 
-func run(s stater, r runner, wdChanger dirGetChanger, configDir, installPath string, hook string, args ...string) error {
-	hookPath := filepath.Join(configDir, "hooks", hook)
+// scripts lists the scripts of a hook in dir: the file named after the hook,
+// then the files of the directory named after the hook with a .d suffix, in lexical order.
+// Hidden files are ignored.
+func scripts(s stater, d dirReader, dir, hook string) ([]Script, error) {
+	found := []Script{}
+	add := func(path string) error {
+		stat, err := s.Stat(path)
+		if err != nil {
+			return err
+		}
+		found = append(found, Script{
+			Hook:       hook,
+			Path:       path,
+			Executable: !stat.IsDir() && stat.Mode().Perm()&0100 == 0100,
+		})
+		return nil
+	}
+	if err := add(filepath.Join(dir, hook)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	entries, err := d.ReadDir(filepath.Join(dir, hook+".d"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if err := add(filepath.Join(dir, hook+".d", entry.Name())); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// Has tells whether scripts are installed for the hook
+func Has(hook string) bool {
+	found, err := ScriptsIn(Dir(), hook)
+	// hooks that can not be listed fail when run
+	return err != nil || len(found) > 0
+}
+
+// ScriptsIn lists the scripts run for a hook from the hooks directory dir
+func ScriptsIn(dir, hook string) ([]Script, error) {
+	return scripts(osStater{}, osDirReader{}, dir, hook)
+}
+
+// Dir returns the directory of hooks
+func Dir() string {
+	return filepath.Join(config.ConfigDir(), "hooks")
+}
+
+func runScripts(s stater, d dirReader, r runner, wdChanger dirGetChanger, configDir, installPath string, continueOnError bool, hook string, args ...string) error {
+	found, err := scripts(s, d, filepath.Join(configDir, "hooks"), hook)
+	if err != nil {
+		return fmt.Errorf("unable to list %s hooks: %s", hook, err.Error())
+	}
+	if len(found) == 0 {
+		return nil
+	}
 	wd, err := wdChanger.Getwd()
 	if err != nil {
 		return fmt.Errorf("unable to get current directory: %s", err.Error())
@@ -60,17 +229,37 @@ func run(s stater, r runner, wdChanger dirGetChanger, configDir, installPath str
 	defer func() {
 		wdChanger.Chdir(wd)
 	}()
-	if stat, err := s.Stat(hookPath); err == nil {
-		if stat.IsDir() || stat.Mode().Perm()&0100 != 0100 {
-			return fmt.Errorf("%s: file is not executable", hookPath)
+	failures := []string{}
+	for _, script := range found {
+		if !script.Executable {
+			err = fmt.Errorf("%s: file is not executable", script.Path)
+		} else if err = r.Run(script.Path, args...); err != nil {
+			err = fmt.Errorf("%s: %s", script.Path, err.Error())
 		}
-		if err := r.Run(hookPath, args...); err != nil {
-			return fmt.Errorf("%s: %s", hookPath, err.Error())
+		if err != nil {
+			if !continueOnError {
+				return err
+			}
+			failures = append(failures, err.Error())
 		}
 	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "\n"))
+	}
 	return nil
 }
 
-func Run(hook string, args ...string) error {
-	return run(osStater{}, execRunner{}, osDirGetChanger{}, config.ConfigDir(), config.GetConfig().InstallPath, hook, args...)
+// Run runs the scripts of a hook from the install path, as configured in the whalebrew configuration.
+// The scripts get the payload as JSON on their standard input and in WHALEBREW_* environment variables.
+func Run(hook string, payload Payload, args ...string) error {
+	c := config.GetConfig()
+	payload.Hook = hook
+	payload.InstallPath = c.InstallPath
+	payload.Version = version.Version
+	stdin, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	r := execRunner{Timeout: c.Hooks.Timeout, Stdin: stdin, Env: payload.Env()}
+	return runScripts(osStater{}, osDirReader{}, r, osDirGetChanger{}, config.ConfigDir(), c.InstallPath, c.Hooks.ContinueOnError, hook, args...)
 }
@@ -1,13 +1,18 @@
 package hooks
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/version"
 )
 
 type testRunner struct {
@@ -64,6 +69,15 @@ func (testFileInfo) Sys() interface{} {
 	return nil
 }
 
+type testDirReader map[string][]os.DirEntry
+
+func (tdr testDirReader) ReadDir(path string) ([]os.DirEntry, error) {
+	if entries, ok := tdr[path]; ok {
+		return entries, nil
+	}
+	return nil, os.ErrNotExist
+}
+
 type testDirChanger struct {
 	t         *testing.T
 	cwd       string
@@ -87,22 +101,26 @@ func TestRun(t *testing.T) {
 	t.Run("When the hook exists", func(t *testing.T) {
 		assert.NoError(
 			t,
-			run(
+			runScripts(
 				testStater{t, testFileInfo{os.FileMode(0700), false}, nil, "/home/user/.whalebrew/hooks/post-install"},
+				testDirReader{},
 				testRunner{t, nil, "/home/user/.whalebrew/hooks/post-install", nil},
 				osDirGetChanger{},
 				"/home/user/.whalebrew",
 				"/tmp",
+				false,
 				"post-install"),
 		)
 		assert.NoError(
 			t,
-			run(
+			runScripts(
 				testStater{t, testFileInfo{os.FileMode(0700), false}, nil, "/home/other/.whalebrew/hooks/post-install"},
+				testDirReader{},
 				testRunner{t, nil, "/home/other/.whalebrew/hooks/post-install", []string{"an-argument"}},
 				&testDirChanger{t, "some/path", []string{"/tmp", "some/path"}, nil, nil},
 				"/home/other/.whalebrew",
 				"/tmp",
+				false,
 				"post-install",
 				"an-argument"),
 		)
@@ -110,12 +128,14 @@ func TestRun(t *testing.T) {
 	t.Run("When failing to get current directory", func(t *testing.T) {
 		assert.Error(
 			t,
-			run(
+			runScripts(
 				testStater{t, testFileInfo{os.FileMode(0600), false}, nil, "/home/other/.whalebrew/hooks/post-install"},
+				testDirReader{},
 				testRunner{t, nil, "/tmp/.whalebrew/hooks/post-install", nil},
 				&testDirChanger{t, "should-be-ignored", nil, fmt.Errorf("testError"), nil},
 				"/home/other/.whalebrew",
 				"/tmp",
+				false,
 				"post-install",
 				"an-argument"),
 		)
@@ -123,12 +143,14 @@ func TestRun(t *testing.T) {
 	t.Run("When failing to change directory", func(t *testing.T) {
 		assert.Error(
 			t,
-			run(
+			runScripts(
 				testStater{t, testFileInfo{os.FileMode(0600), false}, nil, "/home/other/.whalebrew/hooks/post-install"},
+				testDirReader{},
 				testRunner{t, nil, "should-be-ignored", nil},
 				&testDirChanger{t, "should-be-ignored", []string{"/tmp/whalebrew"}, nil, fmt.Errorf("testError")},
 				"/home/other/.whalebrew",
 				"/tmp/whalebrew",
+				false,
 				"post-install",
 				"an-argument"),
 		)
@@ -136,12 +158,14 @@ func TestRun(t *testing.T) {
 	t.Run("When webhook is not executable", func(t *testing.T) {
 		assert.Error(
 			t,
-			run(
+			runScripts(
 				testStater{t, testFileInfo{os.FileMode(0600), false}, nil, "/tmp/whalebrew/hooks/post-install"},
+				testDirReader{},
 				testRunner{t, nil, "should-be-ignored", nil},
 				osDirGetChanger{},
 				"/tmp/whalebrew",
 				"/tmp",
+				false,
 				"post-install",
 				"an-argument"),
 		)
@@ -149,12 +173,14 @@ func TestRun(t *testing.T) {
 	t.Run("When webhook is a directory", func(t *testing.T) {
 		assert.Error(
 			t,
-			run(
+			runScripts(
 				testStater{t, testFileInfo{os.FileMode(0700), true}, nil, "/tmp/whalebrew/hooks/post-install"},
+				testDirReader{},
 				testRunner{t, nil, "should-be-ignored", nil},
 				osDirGetChanger{},
 				"/tmp/whalebrew",
 				"/tmp",
+				false,
 				"post-install",
 				"an-argument"),
 		)
@@ -163,12 +189,14 @@ func TestRun(t *testing.T) {
 	t.Run("When command fails", func(t *testing.T) {
 		assert.Error(
 			t,
-			run(
+			runScripts(
 				testStater{t, testFileInfo{os.FileMode(0700), false}, nil, "/tmp/whalebrew/hooks/post-install"},
+				testDirReader{},
 				testRunner{t, fmt.Errorf("test-error"), "/tmp/whalebrew/hooks/post-install", []string{"an-argument"}},
 				osDirGetChanger{},
 				"/tmp/whalebrew",
 				"/tmp",
+				false,
 				"post-install",
 				"an-argument"),
 		)
@@ -179,11 +207,13 @@ func TestRun(t *testing.T) {
 		os.Setenv("WHALEBREW_CONFIG_DIR", ".")
 		fmt.Println(Run(
 			"post-install",
+			Payload{},
 			"an-argument"))
 		assert.NoError(
 			t,
 			Run(
 				"post-install",
+				Payload{},
 				"an-argument"),
 		)
 	})
@@ -192,4 +222,102 @@ func TestRun(t *testing.T) {
 func TestExecRunner(t *testing.T) {
 	assert.NoError(t, execRunner{}.Run("ls", "-al"))
 	assert.Error(t, execRunner{}.Run("false"))
+	assert.EqualError(t, execRunner{Timeout: 10 * time.Millisecond}.Run("sleep", "5"), "timed out after 10ms")
+}
+
+type recordingRunner struct {
+	ran      []string
+	failures map[string]error
+}
+
+func (rr *recordingRunner) Run(name string, args ...string) error {
+	rr.ran = append(rr.ran, name)
+	return rr.failures[name]
+}
+
+func writeScript(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), mode))
+}
+
+func TestScripts(t *testing.T) {
+	dir := t.TempDir()
+	scripts, err := ScriptsIn(dir, "pre-install")
+	require.NoError(t, err)
+	assert.Empty(t, scripts)
+
+	writeScript(t, filepath.Join(dir, "pre-install"), 0755)
+	writeScript(t, filepath.Join(dir, "pre-install.d", "20-personal"), 0755)
+	writeScript(t, filepath.Join(dir, "pre-install.d", "10-team"), 0644)
+	writeScript(t, filepath.Join(dir, "pre-install.d", ".20-personal.swp"), 0644)
+	scripts, err = ScriptsIn(dir, "pre-install")
+	require.NoError(t, err)
+	assert.Equal(t, []Script{
+		{Hook: "pre-install", Path: filepath.Join(dir, "pre-install"), Executable: true},
+		{Hook: "pre-install", Path: filepath.Join(dir, "pre-install.d", "10-team"), Executable: false},
+		{Hook: "pre-install", Path: filepath.Join(dir, "pre-install.d", "20-personal"), Executable: true},
+	}, scripts)
+}
+
+func TestRunHookDirectory(t *testing.T) {
+	configDir := t.TempDir()
+	dir := filepath.Join(configDir, "hooks", "post-install.d")
+	for _, name := range []string{"30-last", "10-first", "20-failing"} {
+		writeScript(t, filepath.Join(dir, name), 0755)
+	}
+	failing := filepath.Join(dir, "20-failing")
+	runHook := func(continueOnError bool) ([]string, error) {
+		r := &recordingRunner{failures: map[string]error{failing: fmt.Errorf("test-error")}}
+		err := runScripts(osStater{}, osDirReader{}, r, &testDirChanger{t, "some/path", []string{"/tmp", "some/path"}, nil, nil}, configDir, "/tmp", continueOnError, "post-install")
+		return r.ran, err
+	}
+
+	t.Run("When failing fast", func(t *testing.T) {
+		ran, err := runHook(false)
+		assert.EqualError(t, err, failing+": test-error")
+		assert.Equal(t, []string{filepath.Join(dir, "10-first"), failing}, ran)
+	})
+	t.Run("When continuing on error", func(t *testing.T) {
+		ran, err := runHook(true)
+		assert.EqualError(t, err, failing+": test-error")
+		assert.Equal(t, []string{filepath.Join(dir, "10-first"), failing, filepath.Join(dir, "30-last")}, ran)
+	})
+}
+
+func TestRunPayload(t *testing.T) {
+	configDir := t.TempDir()
+	installPath := t.TempDir()
+	t.Setenv("WHALEBREW_CONFIG_DIR", configDir)
+	t.Setenv("WHALEBREW_INSTALL_PATH", installPath)
+	config.Reset()
+	t.Cleanup(config.Reset)
+	out := filepath.Join(configDir, "out")
+	require.NoError(t, os.MkdirAll(filepath.Join(configDir, "hooks"), 0755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(configDir, "hooks", "pre-install"),
+		[]byte("#!/bin/sh\ncat > "+out+".json\necho \"$WHALEBREW_HOOK $WHALEBREW_PACKAGE_NAME $WHALEBREW_PACKAGE_IMAGE $WHALEBREW_PREVIOUS_IMAGE\" > "+out+".env\n"),
+		0755,
+	))
+
+	pkg := &packages.Package{Name: "aws", Image: "whalebrew/awscli:2", Volumes: []string{"~/.ssh:/root/.ssh"}}
+	previous := &packages.Package{Name: "aws", Image: "whalebrew/awscli:1"}
+	require.NoError(t, Run("pre-install", InstallPayload(pkg, previous), pkg.Image, pkg.Name))
+
+	env, err := os.ReadFile(out + ".env")
+	require.NoError(t, err)
+	assert.Equal(t, "pre-install aws whalebrew/awscli:2 whalebrew/awscli:1\n", string(env))
+
+	content, err := os.ReadFile(out + ".json")
+	require.NoError(t, err)
+	payload := Payload{}
+	require.NoError(t, json.Unmarshal(content, &payload))
+	assert.Equal(t, Payload{
+		Hook:        "pre-install",
+		Package:     pkg,
+		Previous:    previous,
+		Permissions: &packages.PermissionDiff{Added: packages.Permissions{Volumes: []string{"~/.ssh:/root/.ssh"}}},
+		InstallPath: installPath,
+		Version:     version.Version,
+	}, payload)
 }
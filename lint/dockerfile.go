@@ -0,0 +1,207 @@
+package lint
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+// ParseDockerfile reads the labels and entrypoint the last stage of a Dockerfile sets.
+// Labels and entrypoints inherited from base images and build arguments are not known.
+func ParseDockerfile(r io.Reader) (*imagev1.Image, error) {
+	image := &imagev1.Image{}
+	image.Config.Labels = map[string]string{}
+	instructions, err := dockerfileInstructions(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, instruction := range instructions {
+		keyword, args := instruction, ""
+		if i := strings.IndexAny(instruction, " \t"); i >= 0 {
+			keyword, args = instruction[:i], strings.TrimSpace(instruction[i:])
+		}
+		keyword = strings.ToUpper(keyword)
+		switch keyword {
+		case "FROM":
+			image.Config.Labels = map[string]string{}
+			image.Config.Entrypoint = nil
+		case "LABEL":
+			labels, err := parseLabels(args)
+			if err != nil {
+				return nil, fmt.Errorf("invalid instruction %q: %w", instruction, err)
+			}
+			for key, value := range labels {
+				image.Config.Labels[key] = value
+			}
+		case "ENTRYPOINT":
+			image.Config.Entrypoint = parseEntrypoint(args)
+		}
+	}
+	return image, nil
+}
+
+// dockerfileInstructions joins continuation lines and drops comments
+func dockerfileInstructions(r io.Reader) ([]string, error) {
+	instructions := []string{}
+	current := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || (line == "" && current != "") {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			current += strings.TrimSuffix(line, "\\")
+			continue
+		}
+		current += line
+		if strings.TrimSpace(current) != "" {
+			instructions = append(instructions, strings.TrimSpace(current))
+		}
+		current = ""
+	}
+	if strings.TrimSpace(current) != "" {
+		instructions = append(instructions, strings.TrimSpace(current))
+	}
+	return instructions, scanner.Err()
+}
+
+// parseLabels parses the arguments of a LABEL instruction,
+// either key=value pairs or the legacy form where the value is the rest of the line
+func parseLabels(args string) (map[string]string, error) {
+	labels := map[string]string{}
+	words, err := splitWords(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("missing label")
+	}
+	if !strings.Contains(words[0], "=") {
+		key, err := unquote(words[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := unquote(strings.TrimSpace(strings.TrimPrefix(args, words[0])))
+		if err != nil {
+			return nil, err
+		}
+		labels[key] = value
+		return labels, nil
+	}
+	for _, word := range words {
+		pair := strings.SplitN(word, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("expecting key=value, got %s", word)
+		}
+		key, err := unquote(pair[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := unquote(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// splitWords splits s on whitespaces that are not quoted, keeping the quotes
+func splitWords(s string) ([]string, error) {
+	words := []string{}
+	word := strings.Builder{}
+	var quote rune
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ' ' || c == '\t':
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(c)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %s", s)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// unquote removes the quotes and escaping backslashes of s
+func unquote(s string) (string, error) {
+	value := strings.Builder{}
+	var quote rune
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			continue
+		case quote != 0 && c == quote:
+			quote = 0
+			continue
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+			continue
+		}
+		value.WriteRune(c)
+	}
+	if quote != 0 {
+		return "", fmt.Errorf("unterminated quote in %s", s)
+	}
+	return value.String(), nil
+}
+
+// parseEntrypoint parses the exec or the shell form of an ENTRYPOINT instruction
+func parseEntrypoint(args string) []string {
+	entrypoint := []string{}
+	if strings.HasPrefix(args, "[") && json.Unmarshal([]byte(args), &entrypoint) == nil {
+		return entrypoint
+	}
+	if args == "" {
+		return nil
+	}
+	return []string{"/bin/sh", "-c", args}
+}
+
+// Dockerfile lints the package a Dockerfile builds
+func Dockerfile(path string, strict bool) ([]Finding, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	image, err := ParseDockerfile(fd)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	findings := []Finding{}
+	packages.LintImage(image, func(e error) {
+		findings = append(findings, NewFinding(path, e, strict))
+	})
+	return findings, nil
+}
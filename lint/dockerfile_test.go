@@ -0,0 +1,93 @@
+package lint_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/lint"
+)
+
+func TestParseDockerfile(t *testing.T) {
+	image, err := lint.ParseDockerfile(strings.NewReader(`
+FROM golang AS build
+LABEL io.whalebrew.config.ports '["8080:8080"]'
+ENTRYPOINT ["go"]
+
+FROM alpine
+# comments are ignored
+LABEL io.whalebrew.config.environment '["TERM", "FOOBAR_NAME"]'
+label io.whalebrew.name="my tool" \
+      io.whalebrew.config.working_dir=/src \
+      # even in continuation lines
+      io.whalebrew.config.keep_container_user=true
+LABEL	maintainer Jane "Doe"
+ENTRYPOINT /usr/bin/tool --verbose
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"io.whalebrew.config.environment":         `["TERM", "FOOBAR_NAME"]`,
+		"io.whalebrew.name":                       "my tool",
+		"io.whalebrew.config.working_dir":         "/src",
+		"io.whalebrew.config.keep_container_user": "true",
+		"maintainer": "Jane Doe",
+	}, image.Config.Labels)
+	assert.Equal(t, []string{"/bin/sh", "-c", "/usr/bin/tool --verbose"}, image.Config.Entrypoint)
+
+	image, err = lint.ParseDockerfile(strings.NewReader("FROM alpine\nENTRYPOINT [\"/usr/bin/tool\", \"--verbose\"]\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/usr/bin/tool", "--verbose"}, image.Config.Entrypoint)
+	assert.Empty(t, image.Config.Labels)
+
+	_, err = lint.ParseDockerfile(strings.NewReader("FROM alpine\nLABEL io.whalebrew.name='tool\n"))
+	assert.Error(t, err)
+	_, err = lint.ParseDockerfile(strings.NewReader("FROM alpine\nLABEL a=b c\n"))
+	assert.Error(t, err)
+}
+
+func TestDockerfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Dockerfile")
+	require.NoError(t, os.WriteFile(path, []byte(`FROM alpine
+LABEL io.whalebrew.config.ports="not a list" io.whalebrew.config.missing_volumes=other io.whalebrew.config.unknown=true
+`), 0644))
+
+	findings, err := lint.Dockerfile(path, false)
+	require.NoError(t, err)
+	rules := map[string]string{}
+	for _, f := range findings {
+		assert.Equal(t, path, f.Image)
+		rules[f.RuleID] = f.Severity
+	}
+	assert.Equal(t, map[string]string{
+		lint.RuleNoEntrypoint:          lint.SeverityError,
+		lint.RuleDecodeFailure:         lint.SeverityError,
+		lint.RuleInvalidMissingVolumes: lint.SeverityError,
+		lint.RuleUnknownLabel:          lint.SeverityWarning,
+	}, rules)
+
+	_, err = lint.Dockerfile(filepath.Join(t.TempDir(), "Dockerfile"), false)
+	assert.Error(t, err)
+}
+
+func TestPackageContent(t *testing.T) {
+	assert.Empty(t, lint.PackageContent("jq", []byte("#!/usr/bin/env whalebrew\nimage: whalebrew/jq\nvolumes:\n- /tmp:/tmp:ro\nresources:\n  cpus: \"1\"\n"), false))
+
+	messages := func(content string) []string {
+		m := []string{}
+		for _, f := range lint.PackageContent("jq", []byte(content), false) {
+			assert.Equal(t, lint.RuleInvalidPackage, f.RuleID)
+			assert.Equal(t, lint.SeverityError, f.Severity)
+			m = append(m, f.Message)
+		}
+		return m
+	}
+	assert.Equal(t, []string{"package files must start with #!/usr/bin/env whalebrew"}, messages("image: whalebrew/jq\n"))
+	assert.Equal(t, []string{"missing image"}, messages("#!/usr/bin/env whalebrew\n"))
+	assert.Len(t, messages("#!/usr/bin/env whalebrew\nimage: whalebrew/jq\nvolume:\n- /tmp:/tmp\n"), 1)
+	assert.Len(t, messages("#!/usr/bin/env whalebrew\nimage: whalebrew/jq\nrequired_version: \"<0.1.0\"\n"), 1)
+	assert.Len(t, messages("#!/usr/bin/env whalebrew\nimage: whalebrew/jq\nvolumes:\n- :/tmp\nresources:\n  cpus: none\n  memory: lots\n"), 3)
+	assert.Len(t, messages("#!/usr/bin/env whalebrew\nimage: whalebrew/jq\nskip_missing_volumes: true\nmount_missing_volumes: true\n"), 1)
+}
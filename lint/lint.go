@@ -0,0 +1,100 @@
+package lint
+
+import (
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+const (
+	RuleNoEntrypoint          = "no-entrypoint"
+	RuleUnknownLabel          = "unknown-label"
+	RuleDecodeFailure         = "decode-failure"
+	RuleInvalidMissingVolumes = "invalid-missing-volumes"
+	RuleInvalidPackage        = "invalid-package"
+	RuleError                 = "error"
+)
+
+// Rule describes a kind of finding
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Rules lists the rules findings may refer to
+var Rules = []Rule{
+	{ID: RuleNoEntrypoint, Description: "The image has no entrypoint to run the package with"},
+	{ID: RuleUnknownLabel, Description: "The image has a whalebrew label whalebrew does not know about"},
+	{ID: RuleDecodeFailure, Description: "The value of a whalebrew label can not be decoded"},
+	{ID: RuleInvalidMissingVolumes, Description: "The missing volumes strategy is not one of error, skip or mount"},
+	{ID: RuleInvalidPackage, Description: "The package file is invalid"},
+	{ID: RuleError, Description: "The package is invalid"},
+}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is a problem found in an image
+type Finding struct {
+	// Image is the image, or the path of the file, the finding is about
+	Image    string `json:"image" yaml:"image"`
+	RuleID   string `json:"rule_id" yaml:"rule_id"`
+	Severity string `json:"severity" yaml:"severity"`
+	Label    string `json:"label,omitempty" yaml:"label,omitempty"`
+	Message  string `json:"message" yaml:"message"`
+	err      error
+}
+
+// NewFinding describes an error reported when linting image.
+// Errors that are not strict are warnings, unless linting in strict mode.
+func NewFinding(image string, err error, strict bool) Finding {
+	f := Finding{Image: image, RuleID: RuleError, Severity: SeverityError, Message: err.Error(), err: err}
+	switch e := err.(type) {
+	case packages.NoEntrypointError:
+		f.RuleID = RuleNoEntrypoint
+	case packages.UnknownLabelError:
+		f.RuleID = RuleUnknownLabel
+		f.Label = e.Label
+	case packages.LabelError:
+		f.RuleID = RuleDecodeFailure
+		if _, ok := e.Err.(packages.MissingVolumesError); ok {
+			f.RuleID = RuleInvalidMissingVolumes
+		}
+		f.Label = e.Label
+	}
+	if s, ok := err.(packages.StrictError); ok && !s.Strict() && !strict {
+		f.Severity = SeverityWarning
+	}
+	return f
+}
+
+// Err returns the error the finding was created from
+func (f Finding) Err() error {
+	return f.err
+}
+
+// Images lints the images, returning the findings in the order they are found
+func Images(inspecter run.ImageInspecter, imageNames []string, strict bool) ([]Finding, error) {
+	findings := []Finding{}
+	for _, imageName := range imageNames {
+		imageInspect, err := inspecter.ImageInspect(imageName)
+		if err != nil {
+			return findings, err
+		}
+		packages.LintImage(imageInspect, func(e error) {
+			findings = append(findings, NewFinding(imageName, e, strict))
+		})
+	}
+	return findings, nil
+}
+
+// Failed reports whether one of the findings is an error
+func Failed(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
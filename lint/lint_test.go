@@ -0,0 +1,153 @@
+package lint_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/lint"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+type testInspecter map[string]*imagev1.Image
+
+func (ti testInspecter) ImageInspect(imageName string) (*imagev1.Image, error) {
+	if image, ok := ti[imageName]; ok {
+		return image, nil
+	}
+	return nil, errors.New("no such image")
+}
+
+func TestNewFinding(t *testing.T) {
+	for name, test := range map[string]struct {
+		err      error
+		strict   bool
+		expected lint.Finding
+	}{
+		"no entrypoint": {
+			err:      packages.NoEntrypointError{},
+			expected: lint.Finding{Image: "jq", RuleID: lint.RuleNoEntrypoint, Severity: lint.SeverityError},
+		},
+		"unknown label": {
+			err:      packages.UnknownLabelError{Label: "io.whalebrew.foo"},
+			expected: lint.Finding{Image: "jq", RuleID: lint.RuleUnknownLabel, Severity: lint.SeverityWarning, Label: "io.whalebrew.foo"},
+		},
+		"unknown label in strict mode": {
+			err:      packages.UnknownLabelError{Label: "io.whalebrew.foo"},
+			strict:   true,
+			expected: lint.Finding{Image: "jq", RuleID: lint.RuleUnknownLabel, Severity: lint.SeverityError, Label: "io.whalebrew.foo"},
+		},
+		"decode failure": {
+			err:      packages.LabelError{Label: "io.whalebrew.config.ports", Err: packages.DecodeLabelError{Value: "[", Err: errors.New("bad")}},
+			expected: lint.Finding{Image: "jq", RuleID: lint.RuleDecodeFailure, Severity: lint.SeverityError, Label: "io.whalebrew.config.ports"},
+		},
+		"invalid missing volumes": {
+			err:      packages.LabelError{Label: "io.whalebrew.config.missing_volumes", Err: packages.MissingVolumesError{Value: "other"}},
+			expected: lint.Finding{Image: "jq", RuleID: lint.RuleInvalidMissingVolumes, Severity: lint.SeverityError, Label: "io.whalebrew.config.missing_volumes"},
+		},
+		"other errors": {
+			err:      errors.New("failure"),
+			expected: lint.Finding{Image: "jq", RuleID: lint.RuleError, Severity: lint.SeverityError},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := lint.NewFinding("jq", test.err, test.strict)
+			assert.Equal(t, test.err, f.Err())
+			assert.Equal(t, test.err.Error(), f.Message)
+			assert.Equal(t, test.expected.Image, f.Image)
+			assert.Equal(t, test.expected.RuleID, f.RuleID)
+			assert.Equal(t, test.expected.Severity, f.Severity)
+			assert.Equal(t, test.expected.Label, f.Label)
+		})
+	}
+}
+
+func TestImages(t *testing.T) {
+	inspecter := testInspecter{
+		"whalebrew/jq": &imagev1.Image{Config: imagev1.ImageConfig{Entrypoint: []string{"jq"}}},
+		"whalebrew/wget": &imagev1.Image{Config: imagev1.ImageConfig{
+			Labels: map[string]string{"io.whalebrew.unknown": "true"},
+		}},
+	}
+	findings, err := lint.Images(inspecter, []string{"whalebrew/jq", "whalebrew/wget"}, false)
+	require.NoError(t, err)
+	require.Len(t, findings, 2)
+	assert.Equal(t, lint.RuleNoEntrypoint, findings[0].RuleID)
+	assert.Equal(t, lint.RuleUnknownLabel, findings[1].RuleID)
+	assert.True(t, lint.Failed(findings))
+	assert.False(t, lint.Failed(findings[1:]))
+
+	_, err = lint.Images(inspecter, []string{"whalebrew/missing"}, false)
+	assert.Error(t, err)
+}
+
+func TestWriteSARIF(t *testing.T) {
+	b := &bytes.Buffer{}
+	require.NoError(t, lint.WriteSARIF(b, []lint.Finding{
+		lint.NewFinding("whalebrew/jq", packages.NoEntrypointError{}, false),
+		lint.NewFinding("whalebrew/jq", packages.UnknownLabelError{Label: "io.whalebrew.foo"}, false),
+	}))
+	log := struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Level     string
+				Message   struct{ Text string }
+				Locations []struct {
+					LogicalLocations []struct {
+						Name               string
+						FullyQualifiedName string
+					}
+				}
+			}
+		}
+	}{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Equal(t, "whalebrew", log.Runs[0].Tool.Driver.Name)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(lint.Rules))
+	results := log.Runs[0].Results
+	require.Len(t, results, 2)
+	assert.Equal(t, lint.RuleNoEntrypoint, results[0].RuleID)
+	assert.Equal(t, "error", results[0].Level)
+	assert.Equal(t, "whalebrew/jq", results[0].Locations[0].LogicalLocations[0].Name)
+	assert.Equal(t, lint.RuleUnknownLabel, results[1].RuleID)
+	assert.Equal(t, "warning", results[1].Level)
+	assert.Equal(t, "unknwon label io.whalebrew.foo", results[1].Message.Text)
+	assert.Equal(t, "whalebrew/jq/io.whalebrew.foo", results[1].Locations[0].LogicalLocations[0].FullyQualifiedName)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	b := &bytes.Buffer{}
+	require.NoError(t, lint.WriteJUnit(b, []string{"whalebrew/jq", "whalebrew/wget"}, []lint.Finding{
+		lint.NewFinding("whalebrew/jq", packages.NoEntrypointError{}, false),
+		lint.NewFinding("whalebrew/jq", packages.UnknownLabelError{Label: "io.whalebrew.foo"}, false),
+	}))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="whalebrew/jq" tests="2" failures="1">
+    <testcase name="no-entrypoint" classname="whalebrew/jq">
+      <failure message="missing entrypoint in docker image. consider re-building using ENTRYPOINT [&#34;/path/to/your/binary&#34;]" type="no-entrypoint">missing entrypoint in docker image. consider re-building using ENTRYPOINT [&#34;/path/to/your/binary&#34;]</failure>
+    </testcase>
+    <testcase name="unknown-label io.whalebrew.foo" classname="whalebrew/jq">
+      <system-out>warning: unknwon label io.whalebrew.foo</system-out>
+    </testcase>
+  </testsuite>
+  <testsuite name="whalebrew/wget" tests="1" failures="0">
+    <testcase name="lint" classname="whalebrew/wget"></testcase>
+  </testsuite>
+</testsuites>
+`, b.String())
+}
@@ -0,0 +1,29 @@
+package lint
+
+import (
+	"os"
+
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+// PackageFile lints an installed or hand edited package file
+func PackageFile(path string, strict bool) ([]Finding, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return PackageContent(path, content, strict), nil
+}
+
+// PackageContent lints the content of a package file
+func PackageContent(path string, content []byte, strict bool) []Finding {
+	findings := []Finding{}
+	packages.LintPackage(content, func(e error) {
+		f := NewFinding(path, e, strict)
+		if f.RuleID == RuleError {
+			f.RuleID = RuleInvalidPackage
+		}
+		findings = append(findings, f)
+	})
+	return findings
+}
@@ -0,0 +1,167 @@
+package lint
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/whalebrew/whalebrew/version"
+)
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// WriteSARIF writes the findings as a SARIF 2.1.0 log.
+// Findings are located in their image, or in the label of the image they relate to.
+func WriteSARIF(w io.Writer, findings []Finding) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "whalebrew",
+			Version:        version.Version,
+			InformationURI: "https://github.com/whalebrew/whalebrew",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, rule := range Rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}})
+	}
+	for _, f := range findings {
+		location := sarifLogicalLocation{Name: f.Image, FullyQualifiedName: f.Image, Kind: "module"}
+		if f.Label != "" {
+			location = sarifLogicalLocation{Name: f.Label, FullyQualifiedName: f.Image + "/" + f.Label, Kind: "member"}
+		}
+		properties := map[string]string{"image": f.Image}
+		if f.Label != "" {
+			properties["label"] = f.Label
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:     f.RuleID,
+			Level:      f.Severity,
+			Message:    sarifMessage{Text: f.Message},
+			Locations:  []sarifLocation{{LogicalLocations: []sarifLogicalLocation{location}}},
+			Properties: properties,
+		})
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// WriteJUnit writes the findings as a JUnit XML report with a test suite per image.
+// Errors are failed test cases, warnings are passing test cases reporting the warning.
+// Images without findings have a single passing test case.
+func WriteJUnit(w io.Writer, imageNames []string, findings []Finding) error {
+	report := junitTestSuites{}
+	for _, image := range imageNames {
+		suite := junitTestSuite{Name: image}
+		for _, f := range findings {
+			if f.Image != image {
+				continue
+			}
+			name := f.RuleID
+			if f.Label != "" {
+				name = fmt.Sprintf("%s %s", f.RuleID, f.Label)
+			}
+			testCase := junitTestCase{Name: name, ClassName: image}
+			if f.Severity == SeverityError {
+				testCase.Failure = &junitFailure{Message: f.Message, Type: f.RuleID, Text: f.Message}
+				suite.Failures++
+			} else {
+				testCase.SystemOut = f.Severity + ": " + f.Message
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		if len(suite.TestCases) == 0 {
+			suite.TestCases = append(suite.TestCases, junitTestCase{Name: "lint", ClassName: image})
+		}
+		suite.Tests = len(suite.TestCases)
+		report.TestSuites = append(report.TestSuites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
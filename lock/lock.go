@@ -0,0 +1,97 @@
+package lock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the lock file in the whalebrew configuration directory
+const FileName = "whalebrew.lock"
+
+// Entry pins a package to the digest its image had when it was installed
+type Entry struct {
+	Image  string `yaml:"image"`
+	Digest string `yaml:"digest"`
+}
+
+// File records the image digest of installed packages
+type File struct {
+	Packages map[string]Entry `yaml:"packages"`
+	path     string
+}
+
+// Path returns the path of the lock file
+func Path() string {
+	return filepath.Join(config.ConfigDir(), FileName)
+}
+
+// Load reads the lock file at the given path. A missing file is considered empty.
+func Load(path string) (*File, error) {
+	f := &File{
+		Packages: map[string]Entry{},
+		path:     path,
+	}
+	d, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(d, f); err != nil {
+		return nil, fmt.Errorf("invalid lock file %s: %v", path, err)
+	}
+	if f.Packages == nil {
+		f.Packages = map[string]Entry{}
+	}
+	return f, nil
+}
+
+// Get returns the lock entry of a package
+func (f *File) Get(name string) (Entry, bool) {
+	e, ok := f.Packages[name]
+	return e, ok
+}
+
+// Set pins a package to the given image digest
+func (f *File) Set(name, image, digest string) {
+	f.Packages[name] = Entry{Image: image, Digest: digest}
+}
+
+// Remove removes the lock entry of a package
+func (f *File) Remove(name string) {
+	delete(f.Packages, name)
+}
+
+// Save writes the lock file, replacing the previous version atomically
+func (f *File) Save() error {
+	d, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, d, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// PinnedImage returns the image reference pinned to the locked digest, like docker.io/whalebrew/jq@sha256:...
+func (e Entry) PinnedImage() (string, error) {
+	ref, err := dockerregistry.ParseReference(e.Image)
+	if err != nil {
+		return "", err
+	}
+	if e.Digest == "" {
+		return "", fmt.Errorf("no digest locked for image %s", e.Image)
+	}
+	return ref.Name() + "@" + e.Digest, nil
+}
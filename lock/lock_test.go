@@ -0,0 +1,48 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", FileName)
+
+	f, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, f.Packages)
+
+	f.Set("jq", "whalebrew/jq", "sha256:1234")
+	f.Set("aws", "whalebrew/awscli:2", "sha256:5678")
+	f.Remove("aws")
+	require.NoError(t, f.Save())
+
+	f, err = Load(path)
+	require.NoError(t, err)
+	entry, ok := f.Get("jq")
+	assert.True(t, ok)
+	assert.Equal(t, Entry{Image: "whalebrew/jq", Digest: "sha256:1234"}, entry)
+	_, ok = f.Get("aws")
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte("packages: [invalid"), 0644))
+	_, err = Load(path)
+	assert.Error(t, err)
+}
+
+func TestPinnedImage(t *testing.T) {
+	image, err := Entry{Image: "whalebrew/jq:1.6", Digest: "sha256:1234"}.PinnedImage()
+	assert.NoError(t, err)
+	assert.Equal(t, "docker.io/whalebrew/jq@sha256:1234", image)
+
+	image, err = Entry{Image: "localhost:5000/some/image", Digest: "sha256:1234"}.PinnedImage()
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:5000/some/image@sha256:1234", image)
+
+	_, err = Entry{Image: "whalebrew/jq"}.PinnedImage()
+	assert.Error(t, err)
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/whalebrew/whalebrew/cmd"
+	"github.com/whalebrew/whalebrew/run"
 )
 
 func main() {
@@ -15,6 +17,10 @@ func main() {
 		err = cmd.RootCmd.Execute()
 	}
 
+	var exitErr run.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.Code)
+	}
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
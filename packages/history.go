@@ -0,0 +1,137 @@
+package packages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Revision is a snapshot of a package file taken before it was replaced or removed
+type Revision struct {
+	Number    int       `json:"number"`
+	Name      string    `json:"name"`
+	Image     string    `json:"image"`
+	Digest    string    `json:"digest,omitempty"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	// Content is the package file as it was installed
+	Content string `json:"content"`
+}
+
+// Package decodes the package of the revision
+func (r *Revision) Package() (*Package, error) {
+	pkg := &Package{
+		WorkingDir: DefaultWorkingDir,
+		Name:       r.Name,
+	}
+	if err := yaml.Unmarshal([]byte(r.Content), pkg); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// HistoryStore keeps the revisions of each package in its own directory
+type HistoryStore struct {
+	Dir string
+}
+
+func (s *HistoryStore) path(name string, number int) string {
+	return filepath.Join(s.Dir, name, strconv.Itoa(number)+".json")
+}
+
+// Revisions lists the revisions of a package, oldest first
+func (s *HistoryStore) Revisions(name string) ([]Revision, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Dir, name))
+	if os.IsNotExist(err) {
+		return []Revision{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	revisions := []Revision{}
+	for _, entry := range entries {
+		number, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		r, err := s.Revision(name, number)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *r)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+	return revisions, nil
+}
+
+// Revision returns a revision of a package
+func (s *HistoryStore) Revision(name string, number int) (*Revision, error) {
+	d, err := os.ReadFile(s.path(name, number))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("package %s has no revision %d", name, number)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &Revision{}
+	if err := json.Unmarshal(d, r); err != nil {
+		return nil, fmt.Errorf("invalid revision %d of package %s: %w", number, name, err)
+	}
+	return r, nil
+}
+
+// Latest returns the most recent revision of a package
+func (s *HistoryStore) Latest(name string) (*Revision, error) {
+	revisions, err := s.Revisions(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("package %s has no revision", name)
+	}
+	return &revisions[len(revisions)-1], nil
+}
+
+// snapshot records the content of a package file as a new revision
+func (s *HistoryStore) snapshot(name string, content []byte, digest, reason string) error {
+	revisions, err := s.Revisions(name)
+	if err != nil {
+		return err
+	}
+	r := Revision{
+		Number:    1,
+		Name:      name,
+		Digest:    digest,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+		Content:   string(content),
+	}
+	if len(revisions) > 0 {
+		r.Number = revisions[len(revisions)-1].Number + 1
+	}
+	if pkg, err := r.Package(); err == nil {
+		r.Image = pkg.Image
+	}
+
+	d, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(s.Dir, name), 0755); err != nil {
+		return err
+	}
+	tmp := s.path(name, r.Number) + ".tmp"
+	if err := os.WriteFile(tmp, d, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(name, r.Number))
+}
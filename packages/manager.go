@@ -7,14 +7,20 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
+	"github.com/whalebrew/whalebrew/config"
 	"gopkg.in/yaml.v3"
 )
 
 // PackageManager manages packages at a given path
 type PackageManager struct {
 	InstallPath string
+	// Metadata records how packages were installed, when not nil
+	Metadata *MetadataStore
+	// History keeps the packages replaced or uninstalled, when not nil
+	History *HistoryStore
 }
 
 type MatchReason string
@@ -30,8 +36,13 @@ type MatchingPackage struct {
 }
 
 // NewPackageManager creates a new PackageManager
+// recording the metadata and history of packages in the whalebrew configuration directory
 func NewPackageManager(path string) *PackageManager {
-	return &PackageManager{InstallPath: path}
+	return &PackageManager{
+		InstallPath: path,
+		Metadata:    &MetadataStore{Dir: filepath.Join(config.ConfigDir(), "metadata")},
+		History:     &HistoryStore{Dir: filepath.Join(config.ConfigDir(), "history")},
+	}
 }
 
 // Looks at installation path for existing installation of pkgName
@@ -46,7 +57,7 @@ func (pm *PackageManager) HasInstallation(pkgName string) bool {
 }
 
 // Install installs a package
-func (pm *PackageManager) Install(pkg *Package) error {
+func (pm *PackageManager) Install(pkg *Package, opts ...InstallOption) error {
 	d, err := yaml.Marshal(&pkg)
 	if err != nil {
 		return err
@@ -59,20 +70,172 @@ func (pm *PackageManager) Install(pkg *Package) error {
 	}
 
 	d = append([]byte("#!/usr/bin/env whalebrew\n"), d...)
-	return ioutil.WriteFile(packagePath, d, 0755)
+	if err := ioutil.WriteFile(packagePath, d, 0755); err != nil {
+		return err
+	}
+	if err := pm.recordMetadata(pkg, opts); err != nil {
+		// leave no package behind so that the installation can be retried
+		os.Remove(packagePath)
+		return err
+	}
+	return nil
 }
 
 // ForceInstall installs a package
-func (pm *PackageManager) ForceInstall(pkg *Package) error {
+func (pm *PackageManager) ForceInstall(pkg *Package, opts ...InstallOption) error {
 	d, err := yaml.Marshal(&pkg)
 	if err != nil {
 		return err
 	}
 
 	packagePath := path.Join(pm.InstallPath, pkg.Name)
+	replaced, digest, isReplaced := pm.revision(pkg.Name)
+	previous, previousErr := ioutil.ReadFile(packagePath)
 
 	d = append([]byte("#!/usr/bin/env whalebrew\n"), d...)
-	return ioutil.WriteFile(packagePath, d, 0755)
+	if err := pm.write(pkg.Name, d); err != nil {
+		return err
+	}
+	if err := pm.recordMetadata(pkg, opts); err != nil {
+		// restore the replaced package so that the installation can be retried
+		if previousErr == nil {
+			pm.write(pkg.Name, previous)
+		} else {
+			os.Remove(packagePath)
+		}
+		return err
+	}
+	if !isReplaced {
+		return nil
+	}
+	// the replaced package is only kept once installed, so that failed installations leave no revision behind
+	return pm.keep(pkg.Name, replaced, digest, "replaced")
+}
+
+// snapshot records the installed package, if any, in the history
+func (pm *PackageManager) snapshot(name, reason string) error {
+	if pm.History == nil {
+		return nil
+	}
+	content, digest, ok := pm.revision(name)
+	if !ok {
+		return nil
+	}
+	return pm.keep(name, content, digest, reason)
+}
+
+// revision reads the installed package, if any, and the digest of its image, to keep them in the history
+func (pm *PackageManager) revision(name string) ([]byte, string, bool) {
+	packagePath := path.Join(pm.InstallPath, name)
+	if isPackage, err := IsPackage(packagePath); err != nil || !isPackage {
+		return nil, "", false
+	}
+	content, err := ioutil.ReadFile(packagePath)
+	if err != nil {
+		return nil, "", false
+	}
+	digest := ""
+	if pm.Metadata != nil {
+		if m, err := pm.Metadata.Load(name); err == nil {
+			digest = m.Digest
+		}
+	}
+	return content, digest, true
+}
+
+// keep records a content of a package in the history
+func (pm *PackageManager) keep(name string, content []byte, digest, reason string) error {
+	if pm.History == nil {
+		return nil
+	}
+	if err := pm.History.snapshot(name, content, digest, reason); err != nil {
+		return fmt.Errorf("failed to keep a revision of %s: %w", name, err)
+	}
+	return nil
+}
+
+// Rollback restores a revision of a package, the latest one when number is 0.
+// The installed package is kept as a new revision before being atomically replaced.
+func (pm *PackageManager) Rollback(name string, number int) (*Revision, error) {
+	if pm.History == nil {
+		return nil, fmt.Errorf("package history is not enabled")
+	}
+	var r *Revision
+	var err error
+	if number == 0 {
+		r, err = pm.History.Latest(name)
+	} else {
+		r, err = pm.History.Revision(name, number)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := r.Package()
+	if err != nil {
+		return nil, fmt.Errorf("invalid revision %d of package %s: %w", r.Number, name, err)
+	}
+
+	if err := pm.replace(name, []byte(r.Content), fmt.Sprintf("rolled back to revision %d", r.Number)); err != nil {
+		return nil, err
+	}
+	return r, pm.recordMetadata(pkg, []InstallOption{WithDigest(r.Digest), WithSource(fmt.Sprintf("rollback to revision %d", r.Number))})
+}
+
+// Edit replaces the content of an installed package, keeping the previous content in the history.
+// The digest of the image is kept as long as the image is not changed.
+func (pm *PackageManager) Edit(name string, content []byte) error {
+	if !pm.HasInstallation(name) {
+		return fmt.Errorf("package %s is not installed", name)
+	}
+	var previous *Metadata
+	if pm.Metadata != nil {
+		previous, _ = pm.Metadata.Load(name)
+	}
+	if err := pm.replace(name, content, "edited"); err != nil {
+		return err
+	}
+	pkg, err := pm.Load(name)
+	if err != nil {
+		return err
+	}
+	opts := []InstallOption{WithSource("edit")}
+	if previous != nil && previous.Image == pkg.Image {
+		// the image, hence its digest, did not change
+		opts = append(opts, WithDigest(previous.Digest))
+	}
+	return pm.recordMetadata(pkg, opts)
+}
+
+// replace atomically replaces the content of a package, keeping the previous content in the history
+func (pm *PackageManager) replace(name string, content []byte, reason string) error {
+	if err := pm.snapshot(name, reason); err != nil {
+		return err
+	}
+	return pm.write(name, content)
+}
+
+// write atomically writes the content of a package
+func (pm *PackageManager) write(name string, content []byte) error {
+	packagePath := path.Join(pm.InstallPath, name)
+	tmp := path.Join(pm.InstallPath, "."+name+".tmp")
+	if err := ioutil.WriteFile(tmp, content, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, packagePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (pm *PackageManager) recordMetadata(pkg *Package, opts []InstallOption) error {
+	if pm.Metadata == nil {
+		return nil
+	}
+	if err := pm.Metadata.record(pkg, opts); err != nil {
+		return fmt.Errorf("failed to record the metadata of %s: %w", pkg.Name, err)
+	}
+	return nil
 }
 
 // List lists installed packages
@@ -125,7 +288,16 @@ func (pm *PackageManager) Uninstall(packageName string) error {
 	if !isPackage {
 		return fmt.Errorf("%s is not a Whalebrew package", p)
 	}
-	return os.Remove(p)
+	if err := pm.snapshot(packageName, "uninstalled"); err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		return err
+	}
+	if pm.Metadata != nil {
+		return pm.Metadata.Remove(packageName)
+	}
+	return nil
 }
 
 // IsPackage returns true if the given path is a whalebrew package
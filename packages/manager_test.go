@@ -9,9 +9,11 @@ import (
 
 	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
+	"github.com/whalebrew/whalebrew/version"
 )
 
 func TestPackageManagerInstall(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
 	installPath, err := ioutil.TempDir("", "whalebrewtest")
 	assert.Nil(t, err)
 	pm := NewPackageManager(installPath)
@@ -51,6 +53,7 @@ func TestPackageManagerInstall(t *testing.T) {
 }
 
 func TestPackageManagerForceInstall(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
 	installPath, err := ioutil.TempDir("", "whalebrewtest")
 	assert.Nil(t, err)
 	pm := NewPackageManager(installPath)
@@ -182,6 +185,7 @@ func TestPackageManagerFindByNameOrImage(t *testing.T) {
 }
 
 func TestPackageManagerUninstall(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
 	installPath, err := ioutil.TempDir("", "whalebrewtest")
 	assert.Nil(t, err)
 	pm := NewPackageManager(installPath)
@@ -223,3 +227,147 @@ func TestIsPackage(t *testing.T) {
 	assert.Nil(t, err)
 	assert.True(t, isPackage)
 }
+
+func TestPackageManagerMetadata(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHALEBREW_CONFIG_DIR", configDir)
+	pm := NewPackageManager(t.TempDir())
+	assert.Equal(t, path.Join(configDir, "metadata"), pm.Metadata.Dir)
+
+	pkg := &Package{Name: "jq", Image: "whalebrew/jq"}
+	assert.NoError(t, pm.Install(pkg, WithDigest("sha256:1234"), WithSource("install"), WithFlags("--name=jq")))
+	m, err := pm.Metadata.Load("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "jq", m.Name)
+	assert.Equal(t, "whalebrew/jq", m.Image)
+	assert.Equal(t, "sha256:1234", m.Digest)
+	assert.Equal(t, "install", m.Source)
+	assert.Equal(t, []string{"--name=jq"}, m.Flags)
+	assert.Equal(t, version.Version, m.InstallerVersion)
+	assert.False(t, m.InstalledAt.IsZero())
+	assert.Equal(t, m.InstalledAt, m.UpdatedAt)
+
+	pkg.Image = "whalebrew/jq:1.6"
+	assert.NoError(t, pm.ForceInstall(pkg, WithSource("upgrade")))
+	updated, err := pm.Metadata.Load("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "whalebrew/jq:1.6", updated.Image)
+	assert.Equal(t, "", updated.Digest)
+	assert.Equal(t, "upgrade", updated.Source)
+	assert.Equal(t, []string{"--name=jq"}, updated.Flags)
+	assert.Equal(t, m.InstalledAt, updated.InstalledAt)
+	assert.False(t, updated.UpdatedAt.Before(m.UpdatedAt))
+
+	assert.NoError(t, pm.Uninstall("jq"))
+	_, err = pm.Metadata.Load("jq")
+	assert.True(t, os.IsNotExist(err))
+
+	// packages are not left behind when their metadata can not be recorded
+	dir := pm.Metadata.Dir
+	pm.Metadata.Dir = path.Join(configDir, "config.yaml", "metadata")
+	assert.NoError(t, os.WriteFile(path.Join(configDir, "config.yaml"), nil, 0644))
+	assert.Error(t, pm.Install(pkg))
+	assert.False(t, pm.HasInstallation("jq"))
+	pm.Metadata.Dir = dir
+	assert.NoError(t, pm.Install(pkg))
+	pm.Metadata.Dir = path.Join(configDir, "config.yaml", "metadata")
+	assert.Error(t, pm.ForceInstall(&Package{Name: "jq", Image: "whalebrew/jq:1.7"}))
+	installed, err := pm.Load("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "whalebrew/jq:1.6", installed.Image)
+	pm.Metadata.Dir = dir
+	assert.NoError(t, pm.Uninstall("jq"))
+
+	pm.Metadata = nil
+	assert.NoError(t, pm.Install(pkg))
+	assert.NoError(t, pm.Uninstall("jq"))
+}
+
+func TestPackageManagerHistory(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
+	installPath := t.TempDir()
+	pm := NewPackageManager(installPath)
+
+	assert.NoError(t, pm.Install(&Package{Name: "jq", Image: "whalebrew/jq:1.5"}, WithDigest("sha256:15")))
+	revisions, err := pm.History.Revisions("jq")
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	assert.NoError(t, pm.ForceInstall(&Package{Name: "jq", Image: "whalebrew/jq:1.6"}, WithDigest("sha256:16")))
+	assert.NoError(t, pm.ForceInstall(&Package{Name: "jq", Image: "whalebrew/jq:1.7"}, WithDigest("sha256:17")))
+	revisions, err = pm.History.Revisions("jq")
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, 1, revisions[0].Number)
+	assert.Equal(t, "whalebrew/jq:1.5", revisions[0].Image)
+	assert.Equal(t, "sha256:15", revisions[0].Digest)
+	assert.Equal(t, "replaced", revisions[0].Reason)
+	assert.Equal(t, "#!/usr/bin/env whalebrew\nimage: whalebrew/jq:1.5\n", revisions[0].Content)
+	assert.Equal(t, 2, revisions[1].Number)
+	assert.Equal(t, "whalebrew/jq:1.6", revisions[1].Image)
+
+	// failed installations keep no revision of the package they did not replace
+	dir := pm.Metadata.Dir
+	pm.Metadata.Dir = path.Join(installPath, "jq", "metadata")
+	assert.Error(t, pm.ForceInstall(&Package{Name: "jq", Image: "whalebrew/jq:1.8"}))
+	pm.Metadata.Dir = dir
+	revisions, err = pm.History.Revisions("jq")
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	r, err := pm.Rollback("jq", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.Number)
+	pkg, err := pm.Load("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "whalebrew/jq:1.5", pkg.Image)
+	m, err := pm.Metadata.Load("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:15", m.Digest)
+	assert.Equal(t, "rollback to revision 1", m.Source)
+
+	latest, err := pm.History.Latest("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, latest.Number)
+	assert.Equal(t, "whalebrew/jq:1.7", latest.Image)
+	assert.Equal(t, "rolled back to revision 1", latest.Reason)
+
+	assert.NoError(t, pm.Uninstall("jq"))
+	latest, err = pm.History.Latest("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, latest.Number)
+	assert.Equal(t, "uninstalled", latest.Reason)
+
+	r, err = pm.Rollback("jq", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, r.Number)
+	pkg, err = pm.Load("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "whalebrew/jq:1.5", pkg.Image)
+
+	_, err = pm.Rollback("jq", 42)
+	assert.Error(t, err)
+	_, err = pm.Rollback("wget", 0)
+	assert.Error(t, err)
+}
+
+func TestPackageManagerEditMetadata(t *testing.T) {
+	t.Setenv("WHALEBREW_CONFIG_DIR", t.TempDir())
+	pm := NewPackageManager(t.TempDir())
+	assert.NoError(t, pm.Install(&Package{Name: "jq", Image: "whalebrew/jq"}, WithDigest("sha256:1234"), WithSource("install"), WithFlags("--name=jq")))
+
+	assert.NoError(t, pm.Edit("jq", []byte("#!/usr/bin/env whalebrew\nimage: whalebrew/jq\nenvironment: [JQ_COLORS]\n")))
+	m, err := pm.Metadata.Load("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "edit", m.Source)
+	assert.Equal(t, "sha256:1234", m.Digest)
+	assert.Equal(t, []string{"--name=jq"}, m.Flags)
+
+	assert.NoError(t, pm.Edit("jq", []byte("#!/usr/bin/env whalebrew\nimage: whalebrew/jq:1.6\n")))
+	m, err = pm.Metadata.Load("jq")
+	assert.NoError(t, err)
+	assert.Equal(t, "whalebrew/jq:1.6", m.Image)
+	assert.Equal(t, "", m.Digest)
+
+	assert.Error(t, pm.Edit("wget", []byte("#!/usr/bin/env whalebrew\nimage: whalebrew/wget\n")))
+}
@@ -0,0 +1,113 @@
+package packages
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/whalebrew/whalebrew/version"
+)
+
+// Metadata records how and when a package was installed
+type Metadata struct {
+	Name             string    `json:"name" yaml:"name"`
+	Image            string    `json:"image" yaml:"image"`
+	Digest           string    `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Source           string    `json:"source,omitempty" yaml:"source,omitempty"`
+	Flags            []string  `json:"flags,omitempty" yaml:"flags,omitempty"`
+	InstallerVersion string    `json:"installer_version" yaml:"installer_version"`
+	InstalledAt      time.Time `json:"installed_at" yaml:"installed_at"`
+	UpdatedAt        time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// InstallOption completes the metadata recorded when installing a package
+type InstallOption func(*Metadata)
+
+// WithDigest records the digest of the image the package was installed from
+func WithDigest(digest string) InstallOption {
+	return func(m *Metadata) {
+		m.Digest = digest
+	}
+}
+
+// WithSource records what installed the package, like the install or upgrade commands
+func WithSource(source string) InstallOption {
+	return func(m *Metadata) {
+		m.Source = source
+	}
+}
+
+// WithFlags records the command line flags the package was installed with
+func WithFlags(flags ...string) InstallOption {
+	return func(m *Metadata) {
+		m.Flags = flags
+	}
+}
+
+// MetadataStore stores the metadata of each package as a JSON file in a directory
+type MetadataStore struct {
+	Dir string
+}
+
+func (s *MetadataStore) path(name string) string {
+	return filepath.Join(s.Dir, name+".json")
+}
+
+// Load returns the metadata of a package, or an error satisfying os.IsNotExist when it has none
+func (s *MetadataStore) Load(name string) (*Metadata, error) {
+	d, err := os.ReadFile(s.path(name))
+	if err != nil {
+		return nil, err
+	}
+	m := &Metadata{}
+	if err := json.Unmarshal(d, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Save writes the metadata of a package, replacing any previous record
+func (s *MetadataStore) Save(m *Metadata) error {
+	d, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	tmp := s.path(m.Name) + ".tmp"
+	if err := os.WriteFile(tmp, d, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(m.Name))
+}
+
+// Remove deletes the metadata of a package, if any
+func (s *MetadataStore) Remove(name string) error {
+	err := os.Remove(s.path(name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// record saves the metadata of an installed package.
+// The installation date, source and flags of a previous record of the package are kept
+// unless provided again, the digest always refers to the new installation.
+func (s *MetadataStore) record(pkg *Package, opts []InstallOption) error {
+	now := time.Now().UTC()
+	m := &Metadata{InstalledAt: now}
+	if previous, err := s.Load(pkg.Name); err == nil {
+		m = previous
+		m.Digest = ""
+	}
+	m.Name = pkg.Name
+	m.Image = pkg.Image
+	m.InstallerVersion = version.Version
+	m.UpdatedAt = now
+	for _, opt := range opts {
+		opt(m)
+	}
+	return s.Save(m)
+}
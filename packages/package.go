@@ -1,9 +1,12 @@
 package packages
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
@@ -28,20 +31,66 @@ var (
 
 // Package represents a Whalebrew package
 type Package struct {
-	Name                string   `yaml:"-" labels:"name"`
-	Entrypoint          []string `yaml:"entrypoint,omitempty"`
-	Environment         []string `yaml:"environment,omitempty" labels:"config.environment"`
-	Image               string   `yaml:"image"`
-	Volumes             []string `yaml:"volumes,omitempty" labels:"config.volumes"`
-	Ports               []string `yaml:"ports,omitempty" labels:"config.ports"`
-	Networks            []string `yaml:"networks,omitempty" labels:"config.networks"`
-	WorkingDir          string   `yaml:"working_dir,omitempty" labels:"config.working_dir"`
-	KeepContainerUser   bool     `yaml:"keep_container_user,omitempty" labels:"config.keep_container_user"`
-	SkipMissingVolumes  bool     `yaml:"skip_missing_volumes,omitempty"`
-	MountMissingVolumes bool     `yaml:"mount_missing_volumes,omitempty"`
-	RequiredVersion     string   `yaml:"required_version,omitempty" labels:"required_version"`
-	PathArguments       []string `yaml:"path_arguments,omitempty" labels:"config.volumes_from_args"`
+	Name                string    `yaml:"-" json:"name" labels:"name"`
+	Entrypoint          []string  `yaml:"entrypoint,omitempty" json:"entrypoint,omitempty"`
+	Environment         []string  `yaml:"environment,omitempty" json:"environment,omitempty" labels:"config.environment"`
+	Image               string    `yaml:"image" json:"image"`
+	Volumes             []string  `yaml:"volumes,omitempty" json:"volumes,omitempty" labels:"config.volumes"`
+	Ports               []string  `yaml:"ports,omitempty" json:"ports,omitempty" labels:"config.ports"`
+	Networks            []string  `yaml:"networks,omitempty" json:"networks,omitempty" labels:"config.networks"`
+	WorkingDir          string    `yaml:"working_dir,omitempty" json:"working_dir,omitempty" labels:"config.working_dir"`
+	KeepContainerUser   bool      `yaml:"keep_container_user,omitempty" json:"keep_container_user,omitempty" labels:"config.keep_container_user"`
+	SkipMissingVolumes  bool      `yaml:"skip_missing_volumes,omitempty" json:"skip_missing_volumes,omitempty"`
+	MountMissingVolumes bool      `yaml:"mount_missing_volumes,omitempty" json:"mount_missing_volumes,omitempty"`
+	RequiredVersion     string    `yaml:"required_version,omitempty" json:"required_version,omitempty" labels:"required_version"`
+	PathArguments       []string  `yaml:"path_arguments,omitempty" json:"path_arguments,omitempty" labels:"config.volumes_from_args"`
+	Runtime             string    `yaml:"runtime,omitempty" json:"runtime,omitempty"`
+	Resources           Resources `yaml:"resources,omitempty" json:"resources,omitempty" labels:"config.resources"`
+	Security            Security  `yaml:"security,omitempty" json:"security,omitempty" labels:"config.security"`
 }
+
+// Resources limits the host resources the package can use
+type Resources struct {
+	CPUs      string `yaml:"cpus,omitempty" json:"cpus,omitempty" labels:"cpus"`
+	Memory    string `yaml:"memory,omitempty" json:"memory,omitempty" labels:"memory"`
+	PidsLimit int64  `yaml:"pids_limit,omitempty" json:"pids_limit,omitempty" labels:"pids_limit"`
+}
+
+// Security hardens the container running the package
+type Security struct {
+	ReadOnly        bool     `yaml:"read_only,omitempty" json:"read_only,omitempty" labels:"read_only"`
+	CapDrop         []string `yaml:"cap_drop,omitempty" json:"cap_drop,omitempty" labels:"cap_drop"`
+	NoNewPrivileges bool     `yaml:"no_new_privileges,omitempty" json:"no_new_privileges,omitempty" labels:"no_new_privileges"`
+}
+
+// IsZero reports whether no resource is limited
+func (r Resources) IsZero() bool {
+	return r == Resources{}
+}
+
+// IsZero reports whether the container is not hardened
+func (s Security) IsZero() bool {
+	return !s.ReadOnly && len(s.CapDrop) == 0 && !s.NoNewPrivileges
+}
+
+// MarshalJSON omits the name, resources and security of the package when they are empty, as the YAML encoding does
+func (pkg Package) MarshalJSON() ([]byte, error) {
+	type fields Package
+	out := struct {
+		Name string `json:"name,omitempty"`
+		fields
+		Resources *Resources `json:"resources,omitempty"`
+		Security  *Security  `json:"security,omitempty"`
+	}{Name: pkg.Name, fields: fields(pkg)}
+	if !pkg.Resources.IsZero() {
+		out.Resources = &pkg.Resources
+	}
+	if !pkg.Security.IsZero() {
+		out.Security = &pkg.Security
+	}
+	return json.Marshal(out)
+}
+
 type StrictError interface {
 	Strict() bool
 }
@@ -87,6 +136,19 @@ func (e UnknownLabelError) Strict() bool {
 	return false
 }
 
+// MissingVolumesError reports an unsupported missing volumes strategy
+type MissingVolumesError struct {
+	Value string
+}
+
+func (e MissingVolumesError) Error() string {
+	return "missing volumes strategy must be one of error, skip or mount"
+}
+
+func (e MissingVolumesError) Strict() bool {
+	return true
+}
+
 type NoEntrypointError struct {
 }
 
@@ -143,6 +205,28 @@ func loadImageLabel(imageInspect *imagev1.Image, label string, dest interface{})
 	return nil
 }
 
+// labelFields calls fn with the label and the address of each labelled field of v.
+// Labels of nested structures are prefixed with the label of the structure.
+func labelFields(v reflect.Value, prefix string, fn func(label string, dest interface{}) error) error {
+	for i := 0; i < v.NumField(); i++ {
+		label := v.Type().Field(i).Tag.Get("labels")
+		if label == "" {
+			continue
+		}
+		field := v.Field(i)
+		var err error
+		if field.Kind() == reflect.Struct {
+			err = labelFields(field, prefix+label+".", fn)
+		} else {
+			err = fn(prefix+label, field.Addr().Interface())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func LintImage(imageInspect *imagev1.Image, reportError func(error)) {
 	if imageInspect == nil || len(imageInspect.Config.Entrypoint) == 0 {
 		reportError(NoEntrypointError{})
@@ -152,24 +236,23 @@ func LintImage(imageInspect *imagev1.Image, reportError func(error)) {
 			for originalLabel, value := range config.Labels {
 				if strings.HasPrefix(originalLabel, labelPrefix) {
 					label := strings.TrimPrefix(originalLabel, labelPrefix)
-					pkg := reflect.ValueOf(&Package{}).Elem()
 					found := false
-					for i := 0; i < pkg.NumField(); i++ {
-						if pkg.Type().Field(i).Tag.Get("labels") == label {
+					labelFields(reflect.ValueOf(&Package{}).Elem(), "", func(fieldLabel string, dest interface{}) error {
+						if fieldLabel == label {
 							found = true
-							err := decodeLabel(value, pkg.Field(i).Addr().Interface())
+							err := decodeLabel(value, dest)
 							if err != nil {
 								reportError(LabelError{Err: err, Label: originalLabel})
 							}
 						}
-					}
+						return nil
+					})
 					if !found && label == "config.missing_volumes" {
 						found = true
 						switch value {
 						case "", "error", "skip", "mount":
-							return
 						default:
-							reportError(LabelError{Err: errors.New("missing volumes strategy must be one of error, skip or mount"), Label: originalLabel})
+							reportError(LabelError{Err: MissingVolumesError{Value: value}, Label: originalLabel})
 						}
 					}
 					if !found {
@@ -181,6 +264,47 @@ func LintImage(imageInspect *imagev1.Image, reportError func(error)) {
 	}
 }
 
+// LintPackage checks the content of a package file, as installed or edited by hand.
+// Unlike loading packages, unknown fields are reported.
+func LintPackage(content []byte, reportError func(error)) {
+	if !bytes.HasPrefix(content, []byte("#!/usr/bin/env whalebrew")) {
+		reportError(errors.New("package files must start with #!/usr/bin/env whalebrew"))
+	}
+	pkg := &Package{}
+	d := yaml.NewDecoder(bytes.NewReader(content))
+	d.KnownFields(true)
+	if err := d.Decode(pkg); err != nil && err != io.EOF {
+		reportError(fmt.Errorf("invalid package: %w", err))
+		return
+	}
+	if pkg.Image == "" {
+		reportError(errors.New("missing image"))
+	}
+	if pkg.RequiredVersion != "" {
+		if err := version.CheckCompatible(pkg.RequiredVersion); err != nil {
+			reportError(err)
+		}
+	}
+	for _, volume := range pkg.Volumes {
+		if _, err := ParseVolume(volume); err != nil {
+			reportError(err)
+		}
+	}
+	if pkg.Resources.CPUs != "" {
+		if _, err := run.ParseCPUs(pkg.Resources.CPUs); err != nil {
+			reportError(err)
+		}
+	}
+	if pkg.Resources.Memory != "" {
+		if _, err := run.ParseMemory(pkg.Resources.Memory); err != nil {
+			reportError(err)
+		}
+	}
+	if pkg.SkipMissingVolumes && pkg.MountMissingVolumes {
+		reportError(errors.New("skip_missing_volumes and mount_missing_volumes can not be both set"))
+	}
+}
+
 // NewPackageFromImage creates a package from a given image name,
 // inspecting the image to fetch the package configuration
 func NewPackageFromImage(image string, imageInspect *imagev1.Image) (*Package, error) {
@@ -195,15 +319,11 @@ func NewPackageFromImage(image string, imageInspect *imagev1.Image) (*Package, e
 		Image: image,
 	}
 
-	v := reflect.ValueOf(pkg).Elem()
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		label := v.Type().Field(i).Tag.Get("labels")
-		if label != "" {
-			if err := loadImageLabel(imageInspect, label, field.Addr().Interface()); err != nil {
-				return nil, err
-			}
-		}
+	err := labelFields(reflect.ValueOf(pkg).Elem(), "", func(label string, dest interface{}) error {
+		return loadImageLabel(imageInspect, label, dest)
+	})
+	if err != nil {
+		return nil, err
 	}
 	missingVolumes := ""
 	if err := loadImageLabel(imageInspect, "config.missing_volumes", &missingVolumes); err != nil {
@@ -275,6 +395,64 @@ func (pkg *Package) PreinstallMessage(prevInstall *Package) string {
 	return permissionReporter.String()
 }
 
+// Permissions are the accesses to the host a package is given
+type Permissions struct {
+	Environment []string `json:"environment,omitempty" yaml:"environment,omitempty"`
+	Ports       []string `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Volumes     []string `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	Networks    []string `json:"networks,omitempty" yaml:"networks,omitempty"`
+}
+
+func (p Permissions) String() string {
+	parts := []string{}
+	for _, permission := range []struct {
+		name   string
+		values []string
+	}{
+		{"ports", p.Ports},
+		{"volumes", p.Volumes},
+		{"env", p.Environment},
+		{"networks", p.Networks},
+	} {
+		if len(permission.values) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", permission.name, strings.Join(permission.values, ",")))
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Permissions returns the accesses to the host the package is given
+func (pkg *Package) Permissions() Permissions {
+	return Permissions{
+		Environment: pkg.Environment,
+		Ports:       pkg.Ports,
+		Volumes:     pkg.Volumes,
+		Networks:    pkg.Networks,
+	}
+}
+
+// PermissionDiff lists the permissions an installation grants and revokes,
+// and the restrictions on its resources and security it relaxes
+type PermissionDiff struct {
+	Added   Permissions `json:"added" yaml:"added"`
+	Removed Permissions `json:"removed" yaml:"removed"`
+	Relaxed []string    `json:"relaxed,omitempty" yaml:"relaxed,omitempty"`
+}
+
+// PermissionDiff compares the permissions of pkg with the ones of prevInstall, if any
+func (pkg *Package) PermissionDiff(prevInstall *Package) PermissionDiff {
+	prev := prevInstall
+	if prev == nil {
+		prev = &Package{}
+	}
+	permissionReporter := NewPermissionChangeReporter(prevInstall == nil)
+	cmp.Equal(prev, pkg, cmp.Reporter(permissionReporter))
+	return permissionReporter.Diff()
+}
+
 func (pkg *Package) HasChanges(ctx context.Context, inspecter run.ImageInspecter) (bool, string, error) {
 	imageInspect, err := inspecter.ImageInspect(pkg.Image)
 	if err != nil {
@@ -286,13 +464,45 @@ func (pkg *Package) HasChanges(ctx context.Context, inspecter run.ImageInspecter
 		return false, "", err
 	}
 
-	if newPkg.WorkingDir == "" {
-		newPkg.WorkingDir = DefaultWorkingDir
+	changed, diff := newPkg.Diff(pkg)
+	return changed, diff, nil
+}
+
+// Upgrade returns the package generated from the current version of its image.
+// The name, entrypoint and runtime chosen at install time are kept.
+// It also reports whether the upgraded package differs from the installed one and how.
+func (pkg *Package) Upgrade(inspecter run.ImageInspecter) (*Package, bool, string, error) {
+	imageInspect, err := inspecter.ImageInspect(pkg.Image)
+	if err != nil {
+		return nil, false, "", err
+	}
+
+	newPkg, err := NewPackageFromImage(pkg.Image, imageInspect)
+	if err != nil {
+		return nil, false, "", err
+	}
+	newPkg.Name = pkg.Name
+	newPkg.Entrypoint = pkg.Entrypoint
+	newPkg.Runtime = pkg.Runtime
+
+	changed, diff := pkg.Diff(newPkg)
+	return newPkg, changed, diff, nil
+}
+
+// Diff reports whether other differs from pkg and describes the changes from pkg to other.
+// Packages without working directory are considered to use the default one.
+func (pkg *Package) Diff(other *Package) (bool, string) {
+	p, c := *pkg, *other
+	if p.WorkingDir == "" {
+		p.WorkingDir = DefaultWorkingDir
+	}
+	if c.WorkingDir == "" {
+		c.WorkingDir = DefaultWorkingDir
 	}
 
 	reporter := NewDiffReporter()
 
-	equal := cmp.Equal(newPkg, pkg, cmp.Reporter(reporter))
+	equal := cmp.Equal(&p, &c, cmp.Reporter(reporter))
 
-	return !equal, reporter.String(), nil
+	return !equal, reporter.String()
 }
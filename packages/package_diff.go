@@ -7,13 +7,13 @@ import (
 	"strings"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/whalebrew/whalebrew/run"
 )
 
 var ErrNotExhaustiveChangeTypeSwitch = errors.New("change type switch is not exhaustive this is likely a programming error")
 
 type fieldChangeReporter func(change StructChange) string
 
-
 type StructChange interface {
 	FieldName() string
 }
@@ -150,33 +150,27 @@ var permissionChangeReporters = map[string]fieldChangeReporter{
 
 		switch v := change.(type) {
 		case Modification:
-			prevVolumeS := v.PrevValue.String()
-			currVolumeS := v.CurrValue.String()
-
-			prevParts := strings.Split(prevVolumeS, ":")
-			currParts := strings.Split(currVolumeS, ":")
-
-			if len(prevParts) > 1 && len(currParts) > 1 {
-				prevRo := strings.HasSuffix(prevVolumeS, "ro")
-				currRo := strings.HasSuffix(currVolumeS, "ro")
+			prev, prevErr := ParseVolume(v.PrevValue.String())
+			curr, currErr := ParseVolume(v.CurrValue.String())
 
-				toRw := prevRo && !currRo
-				toRo := currRo && !prevRo
+			if prevErr == nil && currErr == nil && !prev.IsAnonymous() && !curr.IsAnonymous() {
+				toRw := prev.ReadOnly() && !curr.ReadOnly()
+				toRo := curr.ReadOnly() && !prev.ReadOnly()
 
 				if toRw {
-					fmt.Fprint(&msg, "* Read changed to read and write of the file or directory")
+					fmt.Fprintf(&msg, "* Read changed to read and write of the %s", volumeKind(curr))
 				} else if toRo {
-					fmt.Fprint(&msg, "* Read and write changed to read of the file or directory")
-				} else if currRo {
-					fmt.Fprint(&msg, "* Read the file or directory")
+					fmt.Fprintf(&msg, "* Read and write changed to read of the %s", volumeKind(curr))
+				} else if curr.ReadOnly() {
+					fmt.Fprintf(&msg, "* Read the %s", volumeKind(curr))
 				} else {
-					fmt.Fprint(&msg, "* Read and write to the file or directory")
+					fmt.Fprintf(&msg, "* Read and write to the %s", volumeKind(curr))
 				}
 
-				if prevParts[0] != currParts[0] {
-					fmt.Fprintf(&msg, " changed from %q to %q", prevParts[0], currParts[0])
+				if prev.Host != curr.Host {
+					fmt.Fprintf(&msg, " changed from %q to %q", prev.Host, curr.Host)
 				} else {
-					fmt.Fprintf(&msg, " %q", currParts[0])
+					fmt.Fprintf(&msg, " %q", curr.Host)
 				}
 			}
 
@@ -191,18 +185,112 @@ var permissionChangeReporters = map[string]fieldChangeReporter{
 		}
 
 		return writeValue(volume, func(value reflect.Value, out *strings.Builder) {
-			volumeS := value.String()
-			parts := strings.Split(volumeS, ":")
-
-			if len(parts) > 1 {
-				if strings.HasSuffix(volumeS, "ro") {
-					fmt.Fprintf(out, "* Read the file or directory %q", parts[0])
-				} else {
-					fmt.Fprintf(out, "* Read and write to the file or directory %q", parts[0])
-				}
+			v, err := ParseVolume(value.String())
+			// anonymous volumes do not give access to the host
+			if err != nil || v.IsAnonymous() {
+				return
 			}
+			if v.ReadOnly() {
+				fmt.Fprintf(out, "* Read the %s %q", volumeKind(v), v.Host)
+			} else {
+				fmt.Fprintf(out, "* Read and write to the %s %q", volumeKind(v), v.Host)
+			}
+		})
+	},
+}
+
+// volumeKind names what the host part of a volume refers to in permission messages
+func volumeKind(v Volume) string {
+	if v.IsNamed() {
+		return "volume"
+	}
+	return "file or directory"
+}
+
+var restrictionChangeIterationOrder = []string{
+	"Resources.CPUs", "Resources.Memory", "Resources.PidsLimit",
+	"Security.ReadOnly", "Security.CapDrop", "Security.NoNewPrivileges",
+}
+
+// restrictionChangeReporters describe the changes relaxing a restriction of the package.
+// They return an empty string for changes that keep or tighten the restriction.
+var restrictionChangeReporters = map[string]fieldChangeReporter{
+	"Resources.CPUs": func(change StructChange) string {
+		v, ok := change.(Modification)
+		if !ok || v.PrevValue.String() == "" {
+			return ""
+		}
+		prev, curr := v.PrevValue.String(), v.CurrValue.String()
+		if curr == "" {
+			return fmt.Sprintf("* Use all the CPUs instead of %s", prev)
+		}
+		prevCPUs, prevErr := run.ParseCPUs(prev)
+		currCPUs, currErr := run.ParseCPUs(curr)
+		if prevErr == nil && currErr == nil && currCPUs <= prevCPUs {
+			return ""
+		}
+		return fmt.Sprintf("* Use up to %s CPUs instead of %s", curr, prev)
+	},
+	"Resources.Memory": func(change StructChange) string {
+		v, ok := change.(Modification)
+		if !ok || v.PrevValue.String() == "" {
+			return ""
+		}
+		prev, curr := v.PrevValue.String(), v.CurrValue.String()
+		if curr == "" {
+			return fmt.Sprintf("* Use all the memory instead of %s", prev)
+		}
+		prevMemory, prevErr := run.ParseMemory(prev)
+		currMemory, currErr := run.ParseMemory(curr)
+		if prevErr == nil && currErr == nil && currMemory <= prevMemory {
+			return ""
+		}
+		return fmt.Sprintf("* Use up to %s of memory instead of %s", curr, prev)
+	},
+	"Resources.PidsLimit": func(change StructChange) string {
+		v, ok := change.(Modification)
+		if !ok || v.PrevValue.Int() == 0 {
+			return ""
+		}
+		prev, curr := v.PrevValue.Int(), v.CurrValue.Int()
+		if curr == 0 {
+			return fmt.Sprintf("* Run any number of processes instead of %d", prev)
+		}
+		if curr <= prev {
+			return ""
+		}
+		return fmt.Sprintf("* Run up to %d processes instead of %d", curr, prev)
+	},
+	"Security.ReadOnly": func(change StructChange) string {
+		if v, ok := change.(Modification); ok && v.PrevValue.Bool() && !v.CurrValue.Bool() {
+			return "* Write to the container file system"
+		}
+		return ""
+	},
+	"Security.CapDrop": func(change StructChange) string {
+		var capability reflect.Value
+
+		switch v := change.(type) {
+		case Modification:
+			capability = v.PrevValue
+		case Addition:
+			return ""
+		case Removal:
+			capability = v.RemovedValue
+		default:
+			panic(ErrNotExhaustiveChangeTypeSwitch)
+		}
+
+		return writeValue(capability, func(value reflect.Value, out *strings.Builder) {
+			fmt.Fprintf(out, "* Keep the capability %s", value.String())
 		})
 	},
+	"Security.NoNewPrivileges": func(change StructChange) string {
+		if v, ok := change.(Modification); ok && v.PrevValue.Bool() && !v.CurrValue.Bool() {
+			return "* Gain new privileges through setuid or setgid binaries"
+		}
+		return ""
+	},
 }
 
 func writeValue(val reflect.Value, printer func(reflect.Value, *strings.Builder)) string {
@@ -212,11 +300,16 @@ func writeValue(val reflect.Value, printer func(reflect.Value, *strings.Builder)
 		for i := 0; i < totalElements; i++ {
 			element := val.Index(i)
 
-			printer(element, &msg)
+			var line strings.Builder
+			printer(element, &line)
+			if line.Len() == 0 {
+				continue
+			}
 
-			if i < totalElements-1 {
+			if msg.Len() > 0 {
 				msg.WriteRune('\n')
 			}
+			msg.WriteString(line.String())
 		}
 	} else {
 		printer(val, &msg)
@@ -229,6 +322,17 @@ func isIndexableType(t reflect.Type) bool {
 	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
 }
 
+// fieldName returns the dotted name of the struct fields along path
+func fieldName(path cmp.Path) string {
+	names := []string{}
+	for _, step := range path {
+		if field, ok := step.(cmp.StructField); ok {
+			names = append(names, field.Name())
+		}
+	}
+	return strings.Join(names, ".")
+}
+
 type DiffReporter struct {
 	path  cmp.Path
 	diffs map[string][]StructChange
@@ -256,7 +360,7 @@ func reportStructChange(path cmp.Path) StructChange {
 			panic(errors.New("can not compare with non struct type root value"))
 		}
 
-		fieldName := strings.TrimLeft(parent.String(), ".")
+		fieldName := fieldName(path)
 
 		ix, iy := v.SplitKeys()
 
@@ -283,7 +387,7 @@ func reportStructChange(path cmp.Path) StructChange {
 		}
 
 	case cmp.StructField:
-		fieldName := strings.TrimLeft(leaf.String(), ".")
+		fieldName := fieldName(path)
 
 		if !vx.IsValid() || (isIndexableType(vx.Type()) && isIndexableType(vy.Type()) && vx.Len() == 0 && vy.Len() > 0) {
 			change = Addition{
@@ -402,6 +506,18 @@ func (r *PermissionChangeReporter) PopStep() {
 	r.path = r.path[:len(r.path)-1]
 }
 
+func (r *PermissionChangeReporter) writeAdditionsHeader(additions *strings.Builder, wroteHeader *bool) {
+	if *wroteHeader {
+		return
+	}
+	if r.freshInstall {
+		fmt.Fprint(additions, "This package needs additional access to your system. It wants to:\n\n")
+	} else {
+		fmt.Fprint(additions, "This package update requests additional permissions to the ones it currently has:\n\n")
+	}
+	*wroteHeader = true
+}
+
 func (r *PermissionChangeReporter) String() string {
 	var additions, removals, modifications strings.Builder
 
@@ -414,34 +530,43 @@ func (r *PermissionChangeReporter) String() string {
 
 		for _, change := range changes {
 			if changeReporter, ok := permissionChangeReporters[field]; ok {
+				message := changeReporter(change)
+				if message == "" {
+					continue
+				}
 				switch change.(type) {
 				case Addition:
-					if !wroteHeader[0] {
-						if r.freshInstall {
-							fmt.Fprint(&additions, "This package needs additional access to your system. It wants to:\n\n")
-						} else {
-							fmt.Fprint(&additions, "This package update requests additional permissions to the ones it currently has:\n\n")
-						}
-						wroteHeader[0] = true
-					}
-					fmt.Fprintf(&additions, "%s\n", changeReporter(change))
+					r.writeAdditionsHeader(&additions, &wroteHeader[0])
+					fmt.Fprintf(&additions, "%s\n", message)
 				case Removal:
 					if !wroteHeader[1] {
 						fmt.Fprint(&removals, "Updating this package will remove some of its current permissions:\n\n")
 						wroteHeader[1] = true
 					}
-					fmt.Fprintf(&removals, "%s\n", changeReporter(change))
+					fmt.Fprintf(&removals, "%s\n", message)
 				case Modification:
 					if !wroteHeader[2] {
 						fmt.Fprint(&modifications, "Updating this package will modify some of its current permissions\n\n")
 						wroteHeader[2] = true
 					}
-					fmt.Fprintf(&modifications, "%s\n", changeReporter(change))
+					fmt.Fprintf(&modifications, "%s\n", message)
 				}
 			}
 		}
 	}
 
+	// Relaxing a restriction always grants additional permissions
+	for _, field := range restrictionChangeIterationOrder {
+		for _, change := range r.diffs[field] {
+			message := restrictionChangeReporters[field](change)
+			if message == "" {
+				continue
+			}
+			r.writeAdditionsHeader(&additions, &wroteHeader[0])
+			fmt.Fprintf(&additions, "%s\n", message)
+		}
+	}
+
 	var result []string
 	if additions.Len() > 0 {
 		result = append(result, additions.String())
@@ -457,3 +582,89 @@ func (r *PermissionChangeReporter) String() string {
 
 	return strings.Join(result, "\n")
 }
+
+// permissionFields are the fields of a package listing the accesses to the host it is given
+var permissionFields = []string{"Environment", "Ports", "Volumes", "Networks"}
+
+// Diff lists the permissions the reported changes grant and revoke, and the restrictions they relax
+func (r *PermissionChangeReporter) Diff() PermissionDiff {
+	added := map[string][]string{}
+	removed := map[string][]string{}
+	for _, field := range permissionFields {
+		var fieldAdded, fieldRemoved []string
+		for _, change := range r.diffs[field] {
+			switch v := change.(type) {
+			case Addition:
+				fieldAdded = appendValues(fieldAdded, v.AddedValue)
+			case Removal:
+				fieldRemoved = appendValues(fieldRemoved, v.RemovedValue)
+			case Modification:
+				fieldRemoved = appendValues(fieldRemoved, v.PrevValue)
+				fieldAdded = appendValues(fieldAdded, v.CurrValue)
+			default:
+				panic(ErrNotExhaustiveChangeTypeSwitch)
+			}
+		}
+		// values moved to another index are neither granted nor revoked
+		added[field] = difference(fieldAdded, fieldRemoved)
+		removed[field] = difference(fieldRemoved, fieldAdded)
+	}
+	diff := PermissionDiff{
+		Added: Permissions{
+			Environment: added["Environment"],
+			Ports:       added["Ports"],
+			Volumes:     added["Volumes"],
+			Networks:    added["Networks"],
+		},
+		Removed: Permissions{
+			Environment: removed["Environment"],
+			Ports:       removed["Ports"],
+			Volumes:     removed["Volumes"],
+			Networks:    removed["Networks"],
+		},
+	}
+	for _, field := range restrictionChangeIterationOrder {
+		for _, change := range r.diffs[field] {
+			message := restrictionChangeReporters[field](change)
+			if message == "" {
+				continue
+			}
+			for _, line := range strings.Split(message, "\n") {
+				diff.Relaxed = append(diff.Relaxed, strings.TrimPrefix(line, "* "))
+			}
+		}
+	}
+	return diff
+}
+
+// appendValues appends the string values of val, or of its elements when it is a slice
+func appendValues(values []string, val reflect.Value) []string {
+	if !val.IsValid() {
+		return values
+	}
+	if isIndexableType(val.Type()) {
+		for i := 0; i < val.Len(); i++ {
+			values = append(values, val.Index(i).String())
+		}
+		return values
+	}
+	return append(values, val.String())
+}
+
+// difference returns the values that are not in others
+func difference(values, others []string) []string {
+	var found []string
+	for _, value := range values {
+		known := false
+		for _, other := range others {
+			if value == other {
+				known = true
+				break
+			}
+		}
+		if !known {
+			found = append(found, value)
+		}
+	}
+	return found
+}
@@ -1,6 +1,8 @@
 package packages
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
 
 	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -96,6 +98,25 @@ func TestLintImageSucceedsWithSupportedMissingVolumeStrategy(t *testing.T) {
 	}
 }
 
+func TestLintImageKeepsLintingAfterMissingVolumeStrategy(t *testing.T) {
+	errors := []error{}
+	LintImage(
+		&imagev1.Image{
+			Config: imagev1.ImageConfig{
+				Labels: map[string]string{
+					"io.whalebrew.config.missing_volumes": "skip",
+					"io.whalebrew.config.other":           "value",
+				},
+				Entrypoint: []string{"/entrypoint"},
+			},
+		},
+		func(e error) {
+			errors = append(errors, e)
+		},
+	)
+	assert.Equal(t, []error{UnknownLabelError{"io.whalebrew.config.other"}}, errors)
+}
+
 func TestLintImageErrorsWhenEntrypointIsMissing(t *testing.T) {
 	errored := false
 	LintImage(
@@ -260,6 +281,12 @@ func TestNewPackageFromImage(t *testing.T) {
 	assert.Equal(t, []string{"8100:8100"}, mustNewTestPkg(t, "io.whalebrew.config.ports", `["8100:8100"]`).Ports)
 	assert.Equal(t, []string{"host"}, mustNewTestPkg(t, "io.whalebrew.config.networks", `["host"]`).Networks)
 	assert.Equal(t, []string{"C", "exec-path"}, mustNewTestPkg(t, "io.whalebrew.config.volumes_from_args", `["-C", "--exec-path"]`).PathArguments)
+	assert.Equal(t, "0.5", mustNewTestPkg(t, "io.whalebrew.config.resources.cpus", "0.5").Resources.CPUs)
+	assert.Equal(t, "512m", mustNewTestPkg(t, "io.whalebrew.config.resources.memory", "512m").Resources.Memory)
+	assert.Equal(t, int64(100), mustNewTestPkg(t, "io.whalebrew.config.resources.pids_limit", "100").Resources.PidsLimit)
+	assert.True(t, mustNewTestPkg(t, "io.whalebrew.config.security.read_only", "true").Security.ReadOnly)
+	assert.Equal(t, []string{"ALL"}, mustNewTestPkg(t, "io.whalebrew.config.security.cap_drop", `["ALL"]`).Security.CapDrop)
+	assert.True(t, mustNewTestPkg(t, "io.whalebrew.config.security.no_new_privileges", "true").Security.NoNewPrivileges)
 
 	assert.True(t, mustNewTestPkg(t, "io.whalebrew.config.missing_volumes", "mount").MountMissingVolumes)
 	assert.False(t, mustNewTestPkg(t, "io.whalebrew.config.missing_volumes", "mount").SkipMissingVolumes)
@@ -309,6 +336,98 @@ func TestPreinstallMessage(t *testing.T) {
 			"* Read and write to the file or directory \"/etc/passwd\"\n"+
 			"* Read the file or directory \"/etc/readonly\"\n",
 		pkg.PreinstallMessage(nil))
+
+	pkg = &Package{
+		Volumes: []string{
+			"/cache",
+			"npm-cache:/root/.npm",
+			"~/.aws:/root/.aws:z,ro",
+		},
+	}
+	assert.Equal(t,
+		"This package needs additional access to your system. It wants to:\n"+
+			"\n"+
+			"* Read and write to the volume \"npm-cache\"\n"+
+			"* Read the file or directory \"~/.aws\"\n",
+		pkg.PreinstallMessage(nil))
+
+	update := &Package{
+		Volumes: []string{
+			"/cache",
+			"npm-cache:/root/.npm:ro",
+			"~/.aws:/root/.aws:z,rw",
+		},
+	}
+	assert.Equal(t,
+		"Updating this package will modify some of its current permissions\n"+
+			"\n"+
+			"* Read and write changed to read of the volume \"npm-cache\"\n"+
+			"* Read changed to read and write of the file or directory \"~/.aws\"\n",
+		update.PreinstallMessage(pkg))
+}
+
+func TestPreinstallMessageRestrictions(t *testing.T) {
+	restricted := &Package{
+		Resources: Resources{CPUs: "1", Memory: "512m", PidsLimit: 100},
+		Security:  Security{ReadOnly: true, CapDrop: []string{"ALL"}, NoNewPrivileges: true},
+	}
+	assert.Equal(t, "", restricted.PreinstallMessage(nil))
+
+	tightened := &Package{
+		Resources: Resources{CPUs: "0.5", Memory: "256m", PidsLimit: 50},
+		Security:  Security{ReadOnly: true, CapDrop: []string{"ALL", "NET_RAW"}, NoNewPrivileges: true},
+	}
+	assert.Equal(t, "", tightened.PreinstallMessage(restricted))
+
+	relaxed := &Package{
+		Resources: Resources{CPUs: "2", Memory: "1g"},
+	}
+	assert.Equal(t,
+		"This package update requests additional permissions to the ones it currently has:\n"+
+			"\n"+
+			"* Use up to 2 CPUs instead of 1\n"+
+			"* Use up to 1g of memory instead of 512m\n"+
+			"* Run any number of processes instead of 100\n"+
+			"* Write to the container file system\n"+
+			"* Keep the capability ALL\n"+
+			"* Gain new privileges through setuid or setgid binaries\n",
+		relaxed.PreinstallMessage(restricted))
+}
+
+func TestPermissionDiff(t *testing.T) {
+	pkg := &Package{
+		Environment: []string{"AWS_PROFILE"},
+		Volumes:     []string{"~/.aws:/root/.aws", "~/.ssh:/root/.ssh:ro"},
+	}
+	assert.Equal(t, PermissionDiff{
+		Added: Permissions{Environment: []string{"AWS_PROFILE"}, Volumes: []string{"~/.aws:/root/.aws", "~/.ssh:/root/.ssh:ro"}},
+	}, pkg.PermissionDiff(nil))
+
+	previous := &Package{
+		Volumes: []string{"~/.aws:/root/.aws:ro", "~/.ssh:/root/.ssh:ro"},
+		Ports:   []string{"8080:80"},
+	}
+	assert.Equal(t, PermissionDiff{
+		Added:   Permissions{Environment: []string{"AWS_PROFILE"}, Volumes: []string{"~/.aws:/root/.aws"}},
+		Removed: Permissions{Volumes: []string{"~/.aws:/root/.aws:ro"}, Ports: []string{"8080:80"}},
+	}, pkg.PermissionDiff(previous))
+
+	reordered := &Package{Volumes: []string{"~/.ssh:/root/.ssh:ro", "~/.aws:/root/.aws:ro"}, Ports: []string{"8080:80"}}
+	assert.Equal(t, PermissionDiff{}, reordered.PermissionDiff(previous))
+
+	restricted := &Package{Resources: Resources{CPUs: "1"}, Security: Security{CapDrop: []string{"ALL"}, ReadOnly: true}}
+	relaxed := &Package{Resources: Resources{CPUs: "2"}, Networks: []string{"host"}}
+	assert.Equal(t, PermissionDiff{
+		Added:   Permissions{Networks: []string{"host"}},
+		Relaxed: []string{"Use up to 2 CPUs instead of 1", "Write to the container file system", "Keep the capability ALL"},
+	}, relaxed.PermissionDiff(restricted))
+	assert.Equal(t, PermissionDiff{Removed: Permissions{Networks: []string{"host"}}}, restricted.PermissionDiff(relaxed))
+}
+
+func TestPermissionsString(t *testing.T) {
+	pkg := &Package{Environment: []string{"AWS_PROFILE"}, Volumes: []string{"~/.aws:/root/.aws"}, Networks: []string{"host"}}
+	assert.Equal(t, "volumes: ~/.aws:/root/.aws env: AWS_PROFILE networks: host", pkg.Permissions().String())
+	assert.Equal(t, "none", Permissions{}.String())
 }
 
 func TestLoadPackageFromFile(t *testing.T) {
@@ -321,3 +440,66 @@ func TestLoadPackageFromFile(t *testing.T) {
 	_, err = LoadPackageFromPath("resources/file-that-does-not-exist")
 	assert.Error(t, err)
 }
+
+type testInspecter func(imageName string) (*imagev1.Image, error)
+
+func (ti testInspecter) ImageInspect(imageName string) (*imagev1.Image, error) {
+	return ti(imageName)
+}
+
+func TestUpgrade(t *testing.T) {
+	inspecter := testInspecter(func(imageName string) (*imagev1.Image, error) {
+		assert.Equal(t, "whalebrew/whalesay", imageName)
+		return &imagev1.Image{
+			Config: imagev1.ImageConfig{
+				Labels: map[string]string{"io.whalebrew.config.ports": `["8100:8100"]`},
+			},
+		}, nil
+	})
+
+	t.Run("when the image configuration did not change", func(t *testing.T) {
+		installed := &Package{
+			Name:       "ws",
+			Image:      "whalebrew/whalesay",
+			Entrypoint: []string{"/bin/sh"},
+			Ports:      []string{"8100:8100"},
+			WorkingDir: DefaultWorkingDir,
+		}
+		pkg, changed, diff, err := installed.Upgrade(inspecter)
+		assert.NoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, "", diff)
+		assert.Equal(t, "ws", pkg.Name)
+		assert.Equal(t, []string{"/bin/sh"}, pkg.Entrypoint)
+	})
+
+	t.Run("when the image configuration changed", func(t *testing.T) {
+		installed := &Package{
+			Name:       "whalesay",
+			Image:      "whalebrew/whalesay",
+			WorkingDir: DefaultWorkingDir,
+		}
+		pkg, changed, diff, err := installed.Upgrade(inspecter)
+		assert.NoError(t, err)
+		assert.True(t, changed)
+		assert.Contains(t, diff, "Added these values to Ports")
+		assert.Equal(t, []string{"8100:8100"}, pkg.Ports)
+	})
+
+	t.Run("when the image can not be inspected", func(t *testing.T) {
+		_, _, _, err := (&Package{Image: "whalebrew/whalesay"}).Upgrade(testInspecter(func(string) (*imagev1.Image, error) {
+			return nil, errors.New("test error")
+		}))
+		assert.Error(t, err)
+	})
+}
+
+func TestPackageMarshalJSON(t *testing.T) {
+	d, err := json.Marshal(&Package{Name: "jq", Image: "whalebrew/jq"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"jq","image":"whalebrew/jq"}`, string(d))
+
+	d, err = json.Marshal(Package{Image: "whalebrew/jq", Resources: Resources{Memory: "512m"}, Security: Security{CapDrop: []string{"ALL"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"image":"whalebrew/jq","resources":{"memory":"512m"},"security":{"cap_drop":["ALL"]}}`, string(d))
+}
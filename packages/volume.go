@@ -0,0 +1,93 @@
+package packages
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	namedVolumePattern  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+	windowsDrivePattern = regexp.MustCompile(`^[a-zA-Z]$`)
+)
+
+// Volume is a volume mounted in the container running a package,
+// as provided to the docker -v flag: [HOST:]CONTAINER[:OPTIONS]
+type Volume struct {
+	// Host is the path on the host or the name of the volume, empty for anonymous volumes
+	Host      string
+	Container string
+	Options   []string
+}
+
+// ParseVolume parses a volume definition.
+// Windows paths starting with a drive letter are supported on both sides.
+func ParseVolume(volume string) (Volume, error) {
+	parts := []string{}
+	for _, part := range strings.Split(volume, ":") {
+		// join drive letters with their path, like C:\Users
+		if last := len(parts) - 1; last >= 0 && windowsDrivePattern.MatchString(parts[last]) && (strings.HasPrefix(part, `\`) || strings.HasPrefix(part, "/")) {
+			parts[last] = parts[last] + ":" + part
+			continue
+		}
+		parts = append(parts, part)
+	}
+
+	v := Volume{}
+	switch len(parts) {
+	case 1:
+		v.Container = parts[0]
+	case 2:
+		v.Host, v.Container = parts[0], parts[1]
+	case 3:
+		v.Host, v.Container = parts[0], parts[1]
+		if parts[2] != "" {
+			v.Options = strings.Split(parts[2], ",")
+		}
+	default:
+		return v, fmt.Errorf("invalid volume %q: too many colons", volume)
+	}
+	if v.Container == "" {
+		return v, fmt.Errorf("invalid volume %q: missing container path", volume)
+	}
+	if len(parts) > 1 && v.Host == "" {
+		return v, fmt.Errorf("invalid volume %q: missing host path or volume name", volume)
+	}
+	return v, nil
+}
+
+// IsAnonymous reports whether the volume is created by docker for the container only
+func (v Volume) IsAnonymous() bool {
+	return v.Host == ""
+}
+
+// IsNamed reports whether the host part is a docker volume name rather than a path
+func (v Volume) IsNamed() bool {
+	return namedVolumePattern.MatchString(v.Host) && !windowsDrivePattern.MatchString(v.Host)
+}
+
+// IsBind reports whether the volume binds a file or directory of the host
+func (v Volume) IsBind() bool {
+	return !v.IsAnonymous() && !v.IsNamed()
+}
+
+// ReadOnly reports whether the container can only read the volume
+func (v Volume) ReadOnly() bool {
+	for _, option := range v.Options {
+		if option == "ro" || option == "readonly" {
+			return true
+		}
+	}
+	return false
+}
+
+func (v Volume) String() string {
+	parts := []string{v.Container}
+	if v.Host != "" {
+		parts = append([]string{v.Host}, parts...)
+	}
+	if len(v.Options) > 0 {
+		parts = append(parts, strings.Join(v.Options, ","))
+	}
+	return strings.Join(parts, ":")
+}
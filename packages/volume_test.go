@@ -0,0 +1,63 @@
+package packages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVolume(t *testing.T) {
+	for volume, expected := range map[string]Volume{
+		"/data":                       {Container: "/data"},
+		"/tmp:/tmp":                   {Host: "/tmp", Container: "/tmp"},
+		"~/.aws:/root/.aws:ro":        {Host: "~/.aws", Container: "/root/.aws", Options: []string{"ro"}},
+		"cache:/root/.npm:rw,z":       {Host: "cache", Container: "/root/.npm", Options: []string{"rw", "z"}},
+		`C:\Users\me:/home/me`:        {Host: `C:\Users\me`, Container: "/home/me"},
+		"c:/Users/me:/home/me:ro":     {Host: "c:/Users/me", Container: "/home/me", Options: []string{"ro"}},
+		`C:\data:D:\data`:             {Host: `C:\data`, Container: `D:\data`},
+		"/etc/passwd:/passwdtosteal:": {Host: "/etc/passwd", Container: "/passwdtosteal"},
+	} {
+		t.Run(volume, func(t *testing.T) {
+			v, err := ParseVolume(volume)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, v)
+		})
+	}
+	for _, volume := range []string{"", ":/data", "/data:", "/a:/b:ro:z"} {
+		_, err := ParseVolume(volume)
+		assert.Errorf(t, err, "parsing %q should fail", volume)
+	}
+}
+
+func TestVolumeKind(t *testing.T) {
+	for volume, expected := range map[string][3]bool{
+		"/data":                 {true, false, false},
+		"cache:/root/.npm":      {false, true, false},
+		"my.cache_1:/cache":     {false, true, false},
+		"/tmp:/tmp":             {false, false, true},
+		"./local:/local":        {false, false, true},
+		"~/.aws:/root/.aws":     {false, false, true},
+		`C:\Users\me:/home/me`:  {false, false, true},
+		"$HOME/.aws:/root/.aws": {false, false, true},
+	} {
+		v, err := ParseVolume(volume)
+		assert.NoError(t, err)
+		assert.Equalf(t, expected, [3]bool{v.IsAnonymous(), v.IsNamed(), v.IsBind()}, "kind of volume %q", volume)
+	}
+}
+
+func TestVolumeReadOnly(t *testing.T) {
+	for volume, expected := range map[string]bool{
+		"/tmp:/tmp":          false,
+		"/tmp:/tmp:rw":       false,
+		"/tmp:/tmp:ro":       true,
+		"/tmp:/tmp:z,ro":     true,
+		"/tmp:/tmp:readonly": true,
+		"/tmp:/pro":          false,
+	} {
+		v, err := ParseVolume(volume)
+		assert.NoError(t, err)
+		assert.Equalf(t, expected, v.ReadOnly(), "volume %q being read only", volume)
+		assert.Equal(t, volume, v.String())
+	}
+}
@@ -0,0 +1,335 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FileName is the name of the policy file in the whalebrew configuration directory
+	FileName = "policy.yaml"
+
+	ActionAllow = "allow"
+	ActionWarn  = "warn"
+	ActionDeny  = "deny"
+
+	KindImage       = "image"
+	KindRegistry    = "registry"
+	KindVolume      = "volume"
+	KindEnvironment = "environment"
+	KindNetwork     = "network"
+	KindPort        = "port"
+)
+
+// SystemPath is the path of the system-wide policy file, evaluated before the one of the user
+var SystemPath = "/etc/whalebrew/policy.yaml"
+
+// Rule decides whether packages may be given the permissions it matches.
+// A rule restricted to images and without permissions decides whether the images may be used.
+type Rule struct {
+	Name   string `yaml:"name"`
+	Action string `yaml:"action"`
+	Reason string `yaml:"reason"`
+	// Images restricts the rule to the images matching one of these patterns, like whalebrew/*
+	Images []string `yaml:"images"`
+	// Volumes are patterns of host paths or volume names, like /var/run/docker.sock or ~/.ssh
+	Volumes []string `yaml:"volumes"`
+	// Environment are patterns of environment variable names, like *_TOKEN
+	Environment []string `yaml:"environment"`
+	Networks    []string `yaml:"networks"`
+	Ports       []string `yaml:"ports"`
+}
+
+// File is a policy file
+type File struct {
+	Path string `yaml:"-"`
+	// Registries lists the registries packages may be pulled from, like docker.io or ghcr.io/my-org. Any when empty.
+	Registries []string `yaml:"registries"`
+	Rules      []Rule   `yaml:"rules"`
+}
+
+// Policy evaluates packages against the rules of its files, in order
+type Policy struct {
+	Files []File
+}
+
+// Decision is the action a rule takes on a permission of a package
+type Decision struct {
+	Action string `json:"action" yaml:"action"`
+	Kind   string `json:"kind" yaml:"kind"`
+	Value  string `json:"value" yaml:"value"`
+	Rule   string `json:"rule,omitempty" yaml:"rule,omitempty"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	// Policy is the path of the policy file the rule comes from
+	Policy string `json:"policy" yaml:"policy"`
+}
+
+// Paths returns the paths of the policy files, the system-wide one first
+func Paths() []string {
+	return []string{SystemPath, filepath.Join(config.ConfigDir(), FileName)}
+}
+
+// Load reads the policy files at the given paths. Missing files are ignored.
+func Load(paths ...string) (*Policy, error) {
+	p := &Policy{Files: []File{}}
+	for _, path := range paths {
+		d, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		f := File{}
+		if err := yaml.Unmarshal(d, &f); err != nil {
+			return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+		}
+		f.Path = path
+		for i, rule := range f.Rules {
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("invalid rule %d of policy %s: %v", i+1, path, err)
+			}
+		}
+		p.Files = append(p.Files, f)
+	}
+	return p, nil
+}
+
+// LoadDefault reads the system-wide and the user policy files
+func LoadDefault() (*Policy, error) {
+	return Load(Paths()...)
+}
+
+func (r Rule) validate() error {
+	switch r.Action {
+	case ActionAllow, ActionWarn, ActionDeny:
+	default:
+		return fmt.Errorf("action must be one of %s, %s or %s, got %q", ActionAllow, ActionWarn, ActionDeny, r.Action)
+	}
+	if len(r.Images)+len(r.Volumes)+len(r.Environment)+len(r.Networks)+len(r.Ports) == 0 {
+		return fmt.Errorf("the rule matches nothing")
+	}
+	for _, patterns := range [][]string{r.Images, r.Volumes, r.Environment, r.Networks, r.Ports} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %s: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// patterns returns the patterns of the rule for a kind of permission
+func (r Rule) patterns(kind string) []string {
+	switch kind {
+	case KindImage:
+		if len(r.Volumes)+len(r.Environment)+len(r.Networks)+len(r.Ports) == 0 {
+			return r.Images
+		}
+	case KindVolume:
+		return r.Volumes
+	case KindEnvironment:
+		return r.Environment
+	case KindNetwork:
+		return r.Networks
+	case KindPort:
+		return r.Ports
+	}
+	return nil
+}
+
+// permission is a value a package is given access to, with the values patterns may match
+type permission struct {
+	kind       string
+	value      string
+	candidates []string
+}
+
+// matches reports whether one of the patterns matches the permission.
+// Patterns also match the files and directories below the paths they match.
+// With parents, they also match the directories containing these paths, so that mounting a parent directory
+// does not escape the rules restricting a path.
+func (p permission) matches(patterns []string, parents bool) bool {
+	for _, pattern := range patterns {
+		expanded := []string{pattern}
+		if p.kind == KindVolume {
+			expanded = append(expanded, expandHome(pattern))
+		}
+		for _, pattern := range expanded {
+			for _, candidate := range p.candidates {
+				if p.kind == KindVolume {
+					pattern, candidate = filepath.Clean(pattern), filepath.Clean(candidate)
+				}
+				if ok, _ := path.Match(pattern, candidate); ok {
+					return true
+				}
+				if p.kind == KindVolume && (strings.HasPrefix(candidate, strings.TrimSuffix(pattern, "/")+"/") || (parents && containsMatch(candidate, pattern))) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// containsMatch reports whether dir is a parent directory of the paths pattern matches
+func containsMatch(dir, pattern string) bool {
+	if dir == "/" {
+		return strings.HasPrefix(pattern, "/")
+	}
+	dirParts := strings.Split(dir, "/")
+	patternParts := strings.Split(pattern, "/")
+	if len(patternParts) <= len(dirParts) {
+		return false
+	}
+	ok, _ := path.Match(strings.Join(patternParts[:len(dirParts)], "/"), dir)
+	return ok
+}
+
+// imageMatches reports whether the rule applies to the image
+func (r Rule) imageMatches(image permission) bool {
+	return len(r.Images) == 0 || image.matches(r.Images, false)
+}
+
+// permissions lists the values pkg is given access to
+func permissions(pkg *packages.Package) []permission {
+	image := permission{kind: KindImage, value: pkg.Image, candidates: []string{pkg.Image}}
+	if ref, err := dockerregistry.ParseReference(pkg.Image); err == nil {
+		image.candidates = append(image.candidates, ref.Path, ref.Name())
+	}
+	found := []permission{image}
+	for _, volume := range pkg.Volumes {
+		v, err := packages.ParseVolume(volume)
+		if err != nil || v.IsAnonymous() {
+			continue
+		}
+		found = append(found, permission{kind: KindVolume, value: volume, candidates: []string{v.Host, expandHome(v.Host)}})
+	}
+	for _, env := range pkg.Environment {
+		parts := strings.SplitN(env, "=", 2)
+		candidates := []string{parts[0]}
+		if len(parts) == 2 {
+			// the values of the variables referenced are given to the package too
+			candidates = append(candidates, referencedVariables(parts[1])...)
+		}
+		found = append(found, permission{kind: KindEnvironment, value: env, candidates: candidates})
+	}
+	for _, network := range pkg.Networks {
+		found = append(found, permission{kind: KindNetwork, value: network, candidates: []string{network}})
+	}
+	for _, port := range pkg.Ports {
+		found = append(found, permission{kind: KindPort, value: port, candidates: []string{port, strings.SplitN(port, ":", 2)[0]}})
+	}
+	return found
+}
+
+// referencedVariables lists the names of the environment variables value refers to, like GH_TOKEN in $GH_TOKEN
+func referencedVariables(value string) []string {
+	names := []string{}
+	os.Expand(value, func(name string) string {
+		names = append(names, name)
+		return ""
+	})
+	return names
+}
+
+// expandHome expands the ~ and environment variables of a host path
+func expandHome(host string) string {
+	if strings.HasPrefix(host, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			host = home + host[1:]
+		}
+	}
+	return os.ExpandEnv(host)
+}
+
+// allowedRegistry reports whether the image is hosted in one of the registries
+func allowedRegistry(image string, registries []string) (string, bool) {
+	ref, err := dockerregistry.ParseReference(image)
+	if err != nil {
+		return image, false
+	}
+	name := ref.Name()
+	for _, registry := range registries {
+		registry = strings.TrimSuffix(registry, "/")
+		if ref.Domain == registry || strings.HasPrefix(name, registry+"/") {
+			return ref.Domain, true
+		}
+	}
+	return ref.Domain, false
+}
+
+// Evaluate decides whether pkg complies with the policy.
+// Each permission is decided by the first rule matching it, the rules of the system-wide policy first.
+// Permissions no rule matches are allowed without decision.
+func (p *Policy) Evaluate(pkg *packages.Package) []Decision {
+	decisions := []Decision{}
+	for _, f := range p.Files {
+		if len(f.Registries) == 0 {
+			continue
+		}
+		if domain, ok := allowedRegistry(pkg.Image, f.Registries); !ok {
+			decisions = append(decisions, Decision{
+				Action: ActionDeny,
+				Kind:   KindRegistry,
+				Value:  domain,
+				Reason: fmt.Sprintf("only images from %s are allowed", strings.Join(f.Registries, ", ")),
+				Policy: f.Path,
+			})
+		}
+	}
+	all := permissions(pkg)
+	for _, perm := range all {
+	rules:
+		for _, f := range p.Files {
+			for i, rule := range f.Rules {
+				// allow rules only allow the paths they name, other rules restrict the parent directories too
+				if rule.imageMatches(all[0]) && perm.matches(rule.patterns(perm.kind), rule.Action != ActionAllow) {
+					name := rule.Name
+					if name == "" {
+						name = fmt.Sprintf("#%d", i+1)
+					}
+					decisions = append(decisions, Decision{
+						Action: rule.Action,
+						Kind:   perm.kind,
+						Value:  perm.value,
+						Rule:   name,
+						Reason: rule.Reason,
+						Policy: f.Path,
+					})
+					break rules
+				}
+			}
+		}
+	}
+	return decisions
+}
+
+// EvaluateExecution decides whether running pkg as e complies with the policy.
+// The volumes evaluated are the ones e mounts: expanded, with the working directory and the paths given as arguments.
+func (p *Policy) EvaluateExecution(pkg *packages.Package, e *run.Execution) []Decision {
+	executed := *pkg
+	executed.Volumes = e.Volumes
+	executed.Ports = e.Ports
+	executed.Networks = e.Networks
+	return p.Evaluate(&executed)
+}
+
+// Denied reports whether one of the decisions denies the package
+func Denied(decisions []Decision) bool {
+	for _, d := range decisions {
+		if d.Action == ActionDeny {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	p, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, p.Files)
+
+	_, err = Load(writePolicy(t, "rules:\n- action: forbid\n  networks: [host]\n"))
+	assert.Error(t, err)
+	_, err = Load(writePolicy(t, "rules:\n- action: deny\n"))
+	assert.Error(t, err)
+	_, err = Load(writePolicy(t, "rules:\n- action: deny\n  volumes: ['[']\n"))
+	assert.Error(t, err)
+	_, err = Load(writePolicy(t, "rules: {"))
+	assert.Error(t, err)
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+	system := writePolicy(t, `
+registries: [docker.io, ghcr.io/my-org]
+rules:
+- name: docker-packages
+  action: allow
+  images: [whalebrew/docker]
+  volumes: [/var/run/docker.sock]
+- name: no-docker-socket
+  action: deny
+  reason: packages must not control the docker daemon
+  volumes: [/var/run/docker.sock]
+- name: no-host-network
+  action: deny
+  networks: [host]
+`)
+	user := writePolicy(t, `
+rules:
+- action: warn
+  reason: tokens may leak
+  environment: ["*_TOKEN"]
+- action: allow
+  networks: [host]
+- action: deny
+  volumes: [~/.ssh]
+- action: deny
+  reason: untrusted
+  images: [whalebrew/untrusted]
+`)
+	p, err := Load(system, user)
+	require.NoError(t, err)
+
+	assert.Empty(t, p.Evaluate(&packages.Package{Image: "whalebrew/jq", Volumes: []string{"/tmp:/tmp"}}))
+	assert.Empty(t, p.Evaluate(&packages.Package{Image: "index.docker.io/whalebrew/jq"}), "docker hub aliases are hosted on docker.io")
+	assert.Equal(t, []Decision{{Action: ActionWarn, Kind: KindEnvironment, Value: "GITHUB=${GH_TOKEN}", Rule: "#1", Reason: "tokens may leak", Policy: user}},
+		p.Evaluate(&packages.Package{Image: "whalebrew/gh", Environment: []string{"GITHUB=${GH_TOKEN}"}}))
+	assert.Equal(t, []Decision{{Action: ActionDeny, Kind: KindVolume, Value: "/var/run:/workdir", Rule: "no-docker-socket", Reason: "packages must not control the docker daemon", Policy: system}},
+		p.EvaluateExecution(&packages.Package{Image: "whalebrew/jq"}, &run.Execution{Volumes: []string{"/var/run:/workdir"}}))
+
+	assert.Equal(t, []Decision{{Action: ActionAllow, Kind: KindVolume, Value: "/var/run/docker.sock:/var/run/docker.sock", Rule: "docker-packages", Policy: system}},
+		p.Evaluate(&packages.Package{Image: "whalebrew/docker:20", Volumes: []string{"/var/run/docker.sock:/var/run/docker.sock"}}))
+
+	decisions := p.Evaluate(&packages.Package{
+		Image:       "ghcr.io/my-org/tool",
+		Volumes:     []string{"/var/run/docker.sock:/var/run/docker.sock", "$HOME/.ssh/id_rsa:/root/.ssh/id_rsa:ro"},
+		Environment: []string{"GITHUB_TOKEN", "HOME"},
+		Networks:    []string{"host"},
+	})
+	assert.Equal(t, []Decision{
+		{Action: ActionDeny, Kind: KindVolume, Value: "/var/run/docker.sock:/var/run/docker.sock", Rule: "no-docker-socket", Reason: "packages must not control the docker daemon", Policy: system},
+		{Action: ActionDeny, Kind: KindVolume, Value: "$HOME/.ssh/id_rsa:/root/.ssh/id_rsa:ro", Rule: "#3", Policy: user},
+		{Action: ActionWarn, Kind: KindEnvironment, Value: "GITHUB_TOKEN", Rule: "#1", Reason: "tokens may leak", Policy: user},
+		{Action: ActionDeny, Kind: KindNetwork, Value: "host", Rule: "no-host-network", Policy: system},
+	}, decisions)
+	assert.True(t, Denied(decisions))
+	assert.Equal(t,
+		"The whalebrew policy denies this package to:\n"+
+			"\n"+
+			"* Read and write to the file or directory \"/var/run/docker.sock\": packages must not control the docker daemon (rule no-docker-socket of "+system+")\n"+
+			"* Read the file or directory \"$HOME/.ssh/id_rsa\" (rule #3 of "+user+")\n"+
+			"* Use the network host (rule no-host-network of "+system+")\n"+
+			"\n"+
+			"The whalebrew policy warns this package wants to:\n"+
+			"\n"+
+			"* Read the environment variable GITHUB_TOKEN: tokens may leak (rule #1 of "+user+")\n",
+		Report(decisions))
+
+	decisions = p.Evaluate(&packages.Package{
+		Image:   "whalebrew/docker",
+		Volumes: []string{"/var/run:/var/run", "/var/run/./docker.sock:/sock", "$HOME:/home", "/:/host:ro"},
+	})
+	assert.Equal(t, []Decision{
+		{Action: ActionDeny, Kind: KindVolume, Value: "/var/run:/var/run", Rule: "no-docker-socket", Reason: "packages must not control the docker daemon", Policy: system},
+		{Action: ActionAllow, Kind: KindVolume, Value: "/var/run/./docker.sock:/sock", Rule: "docker-packages", Policy: system},
+		{Action: ActionDeny, Kind: KindVolume, Value: "$HOME:/home", Rule: "#3", Policy: user},
+		{Action: ActionDeny, Kind: KindVolume, Value: "/:/host:ro", Rule: "no-docker-socket", Reason: "packages must not control the docker daemon", Policy: system},
+	}, decisions, "mounting a parent directory or an unclean path does not escape the rules")
+	assert.Equal(t, []Decision{{Action: ActionDeny, Kind: KindVolume, Value: "/var/run/../run/docker.sock:/sock", Rule: "no-docker-socket", Reason: "packages must not control the docker daemon", Policy: system}},
+		p.Evaluate(&packages.Package{Image: "whalebrew/tool", Volumes: []string{"/var/run/../run/docker.sock:/sock"}}))
+
+	decisions = p.Evaluate(&packages.Package{Image: "quay.io/other/untrusted"})
+	assert.Equal(t, []Decision{{Action: ActionDeny, Kind: KindRegistry, Value: "quay.io", Reason: "only images from docker.io, ghcr.io/my-org are allowed", Policy: system}}, decisions)
+	assert.Equal(t, []Decision{{Action: ActionDeny, Kind: KindImage, Value: "whalebrew/untrusted:1.0", Rule: "#4", Reason: "untrusted", Policy: user}},
+		p.Evaluate(&packages.Package{Image: "whalebrew/untrusted:1.0"}))
+	assert.False(t, Denied([]Decision{{Action: ActionWarn}, {Action: ActionAllow}}))
+}
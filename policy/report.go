@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+// describe tells what a permission gives access to, like the permissions shown before installing packages
+func describe(d Decision) string {
+	switch d.Kind {
+	case KindVolume:
+		v, err := packages.ParseVolume(d.Value)
+		if err != nil {
+			return fmt.Sprintf("Mount %q", d.Value)
+		}
+		kind := "file or directory"
+		if v.IsNamed() {
+			kind = "volume"
+		}
+		if v.ReadOnly() {
+			return fmt.Sprintf("Read the %s %q", kind, v.Host)
+		}
+		return fmt.Sprintf("Read and write to the %s %q", kind, v.Host)
+	case KindEnvironment:
+		return fmt.Sprintf("Read the environment variable %s", d.Value)
+	case KindNetwork:
+		return fmt.Sprintf("Use the network %s", d.Value)
+	case KindPort:
+		proto := "TCP"
+		if strings.HasSuffix(d.Value, "udp") {
+			proto = "UDP"
+		}
+		return fmt.Sprintf("Listen on %s port %s", proto, strings.Split(d.Value, ":")[0])
+	case KindRegistry:
+		return fmt.Sprintf("Pull images from %s", d.Value)
+	default:
+		return fmt.Sprintf("Run the image %s", d.Value)
+	}
+}
+
+// Describe describes a decision, with the reason and the rule taking it
+func (d Decision) Describe() string {
+	message := describe(d)
+	if d.Reason != "" {
+		message += ": " + d.Reason
+	}
+	if d.Rule != "" {
+		message += fmt.Sprintf(" (rule %s of %s)", d.Rule, d.Policy)
+	} else {
+		message += fmt.Sprintf(" (%s)", d.Policy)
+	}
+	return message
+}
+
+// Report describes the denied and warned permissions of a package.
+// Allowed permissions are not reported.
+func Report(decisions []Decision) string {
+	var denials, warnings strings.Builder
+	for _, d := range decisions {
+		switch d.Action {
+		case ActionDeny:
+			if denials.Len() == 0 {
+				fmt.Fprint(&denials, "The whalebrew policy denies this package to:\n\n")
+			}
+			fmt.Fprintf(&denials, "* %s\n", d.Describe())
+		case ActionWarn:
+			if warnings.Len() == 0 {
+				fmt.Fprint(&warnings, "The whalebrew policy warns this package wants to:\n\n")
+			}
+			fmt.Fprintf(&warnings, "* %s\n", d.Describe())
+		}
+	}
+	var result []string
+	if denials.Len() > 0 {
+		result = append(result, denials.String())
+	}
+	if warnings.Len() > 0 {
+		result = append(result, warnings.String())
+	}
+	return strings.Join(result, "\n")
+}
@@ -0,0 +1,616 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	dockercliconfig "github.com/docker/cli/cli/config"
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	defaultDockerHost = "unix:///var/run/docker.sock"
+	dockerHubAuthKey  = "https://index.docker.io/v1/"
+)
+
+var errNotFound = errors.New("not found")
+
+// DockerAPI implements the Runner interface talking directly to the docker engine API
+// rather than running the docker command line.
+// See https://docs.docker.com/engine/api/
+type DockerAPI struct {
+	Host   string
+	Client *http.Client
+	Dial   func() (net.Conn, error)
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+var (
+	_ Runner         = &DockerAPI{}
+	_ ImageInspecter = &DockerAPI{}
+	_ ImagePuller    = &DockerAPI{}
+	_ ImageDigester  = &DockerAPI{}
+	_ VolumeCreator  = &DockerAPI{}
+	_ Pinger         = &DockerAPI{}
+)
+
+// NewDockerAPIRunner creates a runner for the docker engine defined by the DOCKER_HOST
+// environment variable, defaulting to the local unix socket
+func NewDockerAPIRunner() (*DockerAPI, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = defaultDockerHost
+	}
+	return NewDockerAPI(host)
+}
+
+// NewDockerAPI creates a runner for the docker engine listening at host.
+// Supported hosts are unix:///path/to/socket and tcp://host:port
+func NewDockerAPI(host string) (*DockerAPI, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %s: %w", host, err)
+	}
+	var dial func() (net.Conn, error)
+	switch u.Scheme {
+	case "unix":
+		dial = func() (net.Conn, error) {
+			return net.Dial("unix", u.Path)
+		}
+	case "tcp":
+		dial = func() (net.Conn, error) {
+			return net.Dial("tcp", u.Host)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported docker host %s: only unix and tcp schemes are supported", host)
+	}
+	return &DockerAPI{
+		Host: host,
+		Client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(context.Context, string, string) (net.Conn, error) {
+					return dial()
+				},
+			},
+		},
+		Dial:   dial,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}, nil
+}
+
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func (d *DockerAPI) request(method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	u := url.URL{Scheme: "http", Host: "docker", Path: path, RawQuery: query.Encode()}
+	req, err := http.NewRequest(method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return d.Client.Do(req)
+}
+
+// call performs a request to the API and decodes the answer in out, when provided
+func (d *DockerAPI) call(method, path string, query url.Values, body interface{}, out interface{}) error {
+	resp, err := d.request(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(method, path, resp); err != nil {
+		return err
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func checkResponse(method, path string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	apiErr := apiError{}
+	json.NewDecoder(resp.Body).Decode(&apiErr)
+	err := fmt.Errorf("%s %s: unexpected status %d: %s", method, path, resp.StatusCode, apiErr.Message)
+	if resp.StatusCode == http.StatusNotFound {
+		err = fmt.Errorf("%w: %v", errNotFound, err)
+	}
+	return err
+}
+
+// Ping checks the docker engine answers
+func (d *DockerAPI) Ping() error {
+	return d.call(http.MethodGet, "/_ping", nil, nil, nil)
+}
+
+// ImageInspect returns the configuration of an image, pulling it when it is not available locally
+func (d *DockerAPI) ImageInspect(imageName string) (*imagev1.Image, error) {
+	image := &imagev1.Image{}
+	err := d.call(http.MethodGet, "/images/"+imageName+"/json", nil, nil, image)
+	if errors.Is(err, errNotFound) {
+		if err := d.ImagePull(imageName); err != nil {
+			return nil, err
+		}
+		image = &imagev1.Image{}
+		err = d.call(http.MethodGet, "/images/"+imageName+"/json", nil, nil, image)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %w", imageName, err)
+	}
+	return image, nil
+}
+
+// ImageRepoDigests returns the repository digests of a local image, without pulling it
+func (d *DockerAPI) ImageRepoDigests(imageName string) ([]string, error) {
+	image := struct {
+		RepoDigests []string
+	}{}
+	err := d.call(http.MethodGet, "/images/"+imageName+"/json", nil, nil, &image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %w", imageName, err)
+	}
+	return image.RepoDigests, nil
+}
+
+// registryAuth returns the credentials of the docker CLI for the registry hosting the image,
+// encoded for the X-Registry-Auth header
+func registryAuth(imageName string) string {
+	server := dockerHubAuthKey
+	if i := strings.Index(imageName, "/"); i >= 0 {
+		domain := imageName[:i]
+		if strings.ContainsAny(domain, ".:") || domain == "localhost" {
+			server = domain
+		}
+	}
+	auth, err := dockercliconfig.LoadDefaultConfigFile(io.Discard).GetAuthConfig(server)
+	if err != nil {
+		return ""
+	}
+	b, err := json.Marshal(auth)
+	if err != nil {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+type pullMessage struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// VolumeCreate creates the named volume with the given labels when it does not exist
+func (d *DockerAPI) VolumeCreate(name string, labels map[string]string) error {
+	err := d.call(http.MethodGet, "/volumes/"+name, nil, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, errNotFound) {
+		return fmt.Errorf("failed to inspect volume %s: %w", name, err)
+	}
+	body := struct {
+		Name   string
+		Labels map[string]string
+	}{Name: name, Labels: labels}
+	if err := d.call(http.MethodPost, "/volumes/create", nil, body, nil); err != nil {
+		return fmt.Errorf("failed to create volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// ImagePull pulls the image from its registry, even if it is already present locally
+func (d *DockerAPI) ImagePull(imageName string) error {
+	u := url.URL{Scheme: "http", Host: "docker", Path: "/images/create", RawQuery: url.Values{"fromImage": {imageName}}.Encode()}
+	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if auth := registryAuth(imageName); auth != "" {
+		req.Header.Set("X-Registry-Auth", auth)
+	}
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download image %s: %w", imageName, err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(http.MethodPost, "/images/create", resp); err != nil {
+		return fmt.Errorf("failed to download image %s: %w", imageName, err)
+	}
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		msg := pullMessage{}
+		err := decoder.Decode(&msg)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to download image %s: %w", imageName, err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("failed to download image %s: %s", imageName, msg.Error)
+		}
+		if msg.ID != "" {
+			fmt.Fprintf(d.Stderr, "%s: %s\n", msg.ID, msg.Status)
+		} else {
+			fmt.Fprintln(d.Stderr, msg.Status)
+		}
+	}
+}
+
+type portBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string
+}
+
+type hostConfig struct {
+	Binds          []string                 `json:",omitempty"`
+	PortBindings   map[string][]portBinding `json:",omitempty"`
+	NetworkMode    string                   `json:",omitempty"`
+	Init           bool
+	AutoRemove     bool
+	NanoCpus       int64    `json:",omitempty"`
+	Memory         int64    `json:",omitempty"`
+	PidsLimit      *int64   `json:",omitempty"`
+	ReadonlyRootfs bool     `json:",omitempty"`
+	CapDrop        []string `json:",omitempty"`
+	SecurityOpt    []string `json:",omitempty"`
+}
+
+type containerConfig struct {
+	Image        string
+	Entrypoint   []string            `json:",omitempty"`
+	Cmd          []string            `json:",omitempty"`
+	Env          []string            `json:",omitempty"`
+	WorkingDir   string              `json:",omitempty"`
+	User         string              `json:",omitempty"`
+	Tty          bool                `json:"Tty"`
+	OpenStdin    bool                `json:"OpenStdin"`
+	StdinOnce    bool                `json:"StdinOnce"`
+	AttachStdin  bool                `json:"AttachStdin"`
+	AttachStdout bool                `json:"AttachStdout"`
+	AttachStderr bool                `json:"AttachStderr"`
+	ExposedPorts map[string]struct{} `json:",omitempty"`
+	Volumes      map[string]struct{} `json:",omitempty"`
+	HostConfig   hostConfig
+}
+
+// parsePort parses a port mapping as provided to docker run -p: [[hostIP:]hostPort:]containerPort[/protocol]
+func parsePort(mapping string) (string, portBinding, error) {
+	proto := "tcp"
+	if i := strings.LastIndex(mapping, "/"); i >= 0 {
+		mapping, proto = mapping[:i], mapping[i+1:]
+	}
+	parts := strings.Split(mapping, ":")
+	binding := portBinding{}
+	containerPort := parts[len(parts)-1]
+	if len(parts) > 1 {
+		binding.HostPort = parts[len(parts)-2]
+	}
+	if len(parts) > 2 {
+		binding.HostIP = strings.Join(parts[:len(parts)-2], ":")
+	}
+	if _, err := strconv.ParseUint(containerPort, 10, 16); err != nil {
+		return "", binding, fmt.Errorf("invalid port mapping %s: %w", mapping, err)
+	}
+	return containerPort + "/" + proto, binding, nil
+}
+
+// resolveEnvironment resolves variables provided without value from the current environment,
+// like docker run -e NAME does
+func resolveEnvironment(vars []string) []string {
+	env := []string{}
+	for _, v := range vars {
+		if strings.Contains(v, "=") {
+			env = append(env, v)
+		} else if value, ok := os.LookupEnv(v); ok {
+			env = append(env, v+"="+value)
+		}
+	}
+	return env
+}
+
+func newContainerConfig(e *Execution) (*containerConfig, error) {
+	c := &containerConfig{
+		Image:        e.Image,
+		Env:          resolveEnvironment(e.Environment),
+		WorkingDir:   e.WorkingDir,
+		Tty:          e.IsTTYOpened,
+		OpenStdin:    true,
+		StdinOnce:    true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		HostConfig: hostConfig{
+			Init:       true,
+			AutoRemove: true,
+		},
+	}
+	args := e.Args
+	if len(e.Entrypoint) > 0 {
+		c.Entrypoint = e.Entrypoint[:1]
+		args = append(append([]string{}, e.Entrypoint[1:]...), args...)
+	}
+	c.Cmd = args
+	for _, mapping := range e.Ports {
+		port, binding, err := parsePort(mapping)
+		if err != nil {
+			return nil, err
+		}
+		if c.ExposedPorts == nil {
+			c.ExposedPorts = map[string]struct{}{}
+			c.HostConfig.PortBindings = map[string][]portBinding{}
+		}
+		c.ExposedPorts[port] = struct{}{}
+		c.HostConfig.PortBindings[port] = append(c.HostConfig.PortBindings[port], binding)
+	}
+	if len(e.Networks) > 0 {
+		c.HostConfig.NetworkMode = e.Networks[0]
+	}
+	for _, volume := range e.Volumes {
+		if strings.Contains(volume, ":") {
+			c.HostConfig.Binds = append(c.HostConfig.Binds, volume)
+		} else {
+			if c.Volumes == nil {
+				c.Volumes = map[string]struct{}{}
+			}
+			c.Volumes[volume] = struct{}{}
+		}
+	}
+	if e.CPUs != "" {
+		cpus, err := ParseCPUs(e.CPUs)
+		if err != nil {
+			return nil, err
+		}
+		c.HostConfig.NanoCpus = int64(cpus * 1e9)
+	}
+	if e.Memory != "" {
+		memory, err := ParseMemory(e.Memory)
+		if err != nil {
+			return nil, err
+		}
+		c.HostConfig.Memory = memory
+	}
+	if e.PidsLimit != 0 {
+		pidsLimit := e.PidsLimit
+		c.HostConfig.PidsLimit = &pidsLimit
+	}
+	c.HostConfig.ReadonlyRootfs = e.ReadOnly
+	c.HostConfig.CapDrop = e.CapDrop
+	if e.NoNewPrivileges {
+		c.HostConfig.SecurityOpt = append(c.HostConfig.SecurityOpt, "no-new-privileges")
+	}
+	if !e.KeepContainerUser && e.User != nil {
+		c.User = e.User.Uid + ":" + e.User.Gid
+	}
+	return c, nil
+}
+
+// hijack performs a request upgrading the connection to a raw stream, as used to attach to containers
+func (d *DockerAPI) hijack(path string, query url.Values) (net.Conn, *bufio.Reader, error) {
+	conn, err := d.Dial()
+	if err != nil {
+		return nil, nil, err
+	}
+	u := url.URL{Scheme: "http", Host: "docker", Path: path, RawQuery: query.Encode()}
+	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, nil, checkResponse(http.MethodPost, path, resp)
+	}
+	return conn, reader, nil
+}
+
+// demultiplex splits the stdout and stderr streams of a container attached without TTY
+// See https://docs.docker.com/engine/api/v1.43/#tag/Container/operation/ContainerAttach
+func demultiplex(stdout, stderr io.Writer, src io.Reader) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(src, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		out := stdout
+		if header[0] == 2 {
+			out = stderr
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(out, src, size); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *DockerAPI) resize(id string, fd int) {
+	width, height, err := terminal.GetSize(fd)
+	if err != nil {
+		return
+	}
+	d.call(http.MethodPost, "/containers/"+id+"/resize", url.Values{"h": {strconv.Itoa(height)}, "w": {strconv.Itoa(width)}}, nil, nil)
+}
+
+// kill forwards a signal received by whalebrew to the container
+func (d *DockerAPI) kill(id string, s os.Signal) {
+	sig, ok := s.(syscall.Signal)
+	if !ok {
+		return
+	}
+	d.call(http.MethodPost, "/containers/"+id+"/kill", url.Values{"signal": {strconv.Itoa(int(sig))}}, nil, nil)
+}
+
+type waitResponse struct {
+	StatusCode int
+	Error      *apiError
+}
+
+// Run runs a given package until completion and removes its container.
+// When the command exits with a non zero status, an ExitError is returned.
+func (d *DockerAPI) Run(e *Execution) error {
+	if e == nil {
+		return fmt.Errorf("no execution provided")
+	}
+	if e.Image == "" {
+		return fmt.Errorf("no image to run")
+	}
+	config, err := newContainerConfig(e)
+	if err != nil {
+		return err
+	}
+
+	created := struct {
+		ID string `json:"Id"`
+	}{}
+	err = d.call(http.MethodPost, "/containers/create", nil, config, &created)
+	if errors.Is(err, errNotFound) {
+		if err := d.ImagePull(e.Image); err != nil {
+			return err
+		}
+		err = d.call(http.MethodPost, "/containers/create", nil, config, &created)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create container: %w", err)
+	}
+	// the daemon removes the container once it exits, this only cleans up containers that never started
+	defer d.call(http.MethodDelete, "/containers/"+created.ID, url.Values{"force": {"1"}, "v": {"1"}}, nil, nil)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for s := range signals {
+			d.kill(created.ID, s)
+		}
+	}()
+
+	if len(e.Networks) > 1 {
+		for _, network := range e.Networks[1:] {
+			err := d.call(http.MethodPost, "/networks/"+network+"/connect", nil, map[string]string{"Container": created.ID}, nil)
+			if err != nil {
+				return fmt.Errorf("failed to connect container to network %s: %w", network, err)
+			}
+		}
+	}
+
+	conn, reader, err := d.hijack("/containers/"+created.ID+"/attach", url.Values{"stream": {"1"}, "stdin": {"1"}, "stdout": {"1"}, "stderr": {"1"}})
+	if err != nil {
+		return fmt.Errorf("failed to attach to container: %w", err)
+	}
+	defer conn.Close()
+
+	if e.IsTTYOpened {
+		if stdin, ok := d.Stdin.(*os.File); ok && terminal.IsTerminal(int(stdin.Fd())) {
+			state, err := terminal.MakeRaw(int(stdin.Fd()))
+			if err == nil {
+				defer terminal.Restore(int(stdin.Fd()), state)
+			}
+			resized := make(chan os.Signal, 1)
+			signal.Notify(resized, syscall.SIGWINCH)
+			defer signal.Stop(resized)
+			go func() {
+				for range resized {
+					d.resize(created.ID, int(stdin.Fd()))
+				}
+			}()
+		}
+	}
+
+	outputDone := make(chan error, 1)
+	go func() {
+		if e.IsTTYOpened {
+			_, err := io.Copy(d.Stdout, reader)
+			outputDone <- err
+		} else {
+			outputDone <- demultiplex(d.Stdout, d.Stderr, reader)
+		}
+	}()
+	go func() {
+		if d.Stdin != nil {
+			io.Copy(conn, d.Stdin)
+		}
+		if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+			closer.CloseWrite()
+		}
+	}()
+
+	// wait for the removal before starting, an auto removed container may be gone by the time it would be waited for
+	waiting, err := d.request(http.MethodPost, "/containers/"+created.ID+"/wait", url.Values{"condition": {"removed"}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to wait for container: %w", err)
+	}
+	defer waiting.Body.Close()
+	if err := checkResponse(http.MethodPost, "/containers/"+created.ID+"/wait", waiting); err != nil {
+		return fmt.Errorf("failed to wait for container: %w", err)
+	}
+
+	if err := d.call(http.MethodPost, "/containers/"+created.ID+"/start", nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	if e.IsTTYOpened {
+		if stdin, ok := d.Stdin.(*os.File); ok && terminal.IsTerminal(int(stdin.Fd())) {
+			d.resize(created.ID, int(stdin.Fd()))
+		}
+	}
+
+	status := waitResponse{}
+	if err := json.NewDecoder(waiting.Body).Decode(&status); err != nil {
+		return fmt.Errorf("failed to wait for container: %w", err)
+	}
+	<-outputDone
+	if status.Error != nil && status.Error.Message != "" {
+		return fmt.Errorf("failed to wait for container: %s", status.Error.Message)
+	}
+	if status.StatusCode != 0 {
+		return ExitError{Code: status.StatusCode}
+	}
+	return nil
+}
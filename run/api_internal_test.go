@@ -0,0 +1,79 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePort(t *testing.T) {
+	for mapping, expected := range map[string]struct {
+		port    string
+		binding portBinding
+	}{
+		"80":                {"80/tcp", portBinding{}},
+		"8080:80":           {"80/tcp", portBinding{HostPort: "8080"}},
+		"127.0.0.1:8080:80": {"80/tcp", portBinding{HostIP: "127.0.0.1", HostPort: "8080"}},
+		"53:53/udp":         {"53/udp", portBinding{HostPort: "53"}},
+		"127.0.0.1::80/tcp": {"80/tcp", portBinding{HostIP: "127.0.0.1"}},
+	} {
+		t.Run(mapping, func(t *testing.T) {
+			port, binding, err := parsePort(mapping)
+			assert.NoError(t, err)
+			assert.Equal(t, expected.port, port)
+			assert.Equal(t, expected.binding, binding)
+		})
+	}
+	_, _, err := parsePort("8080:http")
+	assert.Error(t, err)
+}
+
+func TestResolveEnvironment(t *testing.T) {
+	t.Setenv("WHALEBREW_TEST_SET", "value")
+	assert.Equal(
+		t,
+		[]string{"HELLO=world", "WHALEBREW_TEST_SET=value"},
+		resolveEnvironment([]string{"HELLO=world", "WHALEBREW_TEST_SET", "WHALEBREW_TEST_NOT_SET_AT_ALL"}),
+	)
+}
+
+func TestNewContainerConfig(t *testing.T) {
+	c, err := newContainerConfig(&Execution{
+		Image:             "alpine",
+		Args:              []string{"hello"},
+		Volumes:           []string{"/data", "/tmp:/tmp:ro"},
+		Networks:          []string{"host", "other"},
+		KeepContainerUser: true,
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, c.Entrypoint)
+	assert.Equal(t, []string{"hello"}, c.Cmd)
+	assert.Equal(t, map[string]struct{}{"/data": {}}, c.Volumes)
+	assert.Equal(t, []string{"/tmp:/tmp:ro"}, c.HostConfig.Binds)
+	assert.Equal(t, "host", c.HostConfig.NetworkMode)
+	assert.Equal(t, "", c.User)
+	assert.True(t, c.HostConfig.Init)
+	assert.True(t, c.HostConfig.AutoRemove)
+}
+
+func TestNewContainerConfigLimits(t *testing.T) {
+	c, err := newContainerConfig(&Execution{
+		Image:           "alpine",
+		CPUs:            "1.5",
+		Memory:          "1g",
+		PidsLimit:       100,
+		ReadOnly:        true,
+		CapDrop:         []string{"ALL"},
+		NoNewPrivileges: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500000000), c.HostConfig.NanoCpus)
+	assert.Equal(t, int64(1<<30), c.HostConfig.Memory)
+	assert.Equal(t, int64(100), *c.HostConfig.PidsLimit)
+	assert.True(t, c.HostConfig.ReadonlyRootfs)
+	assert.Equal(t, []string{"ALL"}, c.HostConfig.CapDrop)
+	assert.Equal(t, []string{"no-new-privileges"}, c.HostConfig.SecurityOpt)
+
+	_, err = newContainerConfig(&Execution{Image: "alpine", Memory: "a lot"})
+	assert.Error(t, err)
+}
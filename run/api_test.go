@@ -0,0 +1,257 @@
+package run_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+// newTestEngine starts a fake docker engine listening on a unix socket
+func newTestEngine(t *testing.T, handler http.Handler) *run.DockerAPI {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	d, err := run.NewDockerAPI("unix://" + socket)
+	require.NoError(t, err)
+	d.Stdin = strings.NewReader("")
+	d.Stdout = &bytes.Buffer{}
+	d.Stderr = &bytes.Buffer{}
+	return d
+}
+
+func writeFrame(w *bytes.Buffer, stream byte, content string) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(content)))
+	w.Write(header)
+	w.WriteString(content)
+}
+
+func TestNewDockerAPI(t *testing.T) {
+	_, err := run.NewDockerAPI("unix:///var/run/docker.sock")
+	assert.NoError(t, err)
+	_, err = run.NewDockerAPI("tcp://localhost:2375")
+	assert.NoError(t, err)
+	_, err = run.NewDockerAPI("ssh://user@host")
+	assert.Error(t, err)
+}
+
+func TestDockerAPIImageInspect(t *testing.T) {
+	pulled := false
+	d := newTestEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/images/whalebrew/jq/json":
+			if !pulled {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"message":"No such image: whalebrew/jq"}`))
+				return
+			}
+			w.Write([]byte(`{"Config":{"Entrypoint":["jq"],"Labels":{"foo":"bar"}},"RepoDigests":["whalebrew/jq@sha256:1234"]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/images/create":
+			assert.Equal(t, "whalebrew/jq", r.URL.Query().Get("fromImage"))
+			pulled = true
+			w.Write([]byte(`{"status":"Pulling from whalebrew/jq"}` + "\n" + `{"status":"Download complete","id":"1234"}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	_, err := d.ImageRepoDigests("whalebrew/jq")
+	assert.Error(t, err)
+
+	inspect, err := d.ImageInspect("whalebrew/jq")
+	require.NoError(t, err)
+	assert.True(t, pulled)
+	assert.Equal(t, []string{"jq"}, inspect.Config.Entrypoint)
+	assert.Equal(t, "bar", inspect.Config.Labels["foo"])
+	assert.Contains(t, d.Stderr.(*bytes.Buffer).String(), "1234: Download complete")
+
+	digests, err := d.ImageRepoDigests("whalebrew/jq")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"whalebrew/jq@sha256:1234"}, digests)
+}
+
+func TestDockerAPIVolumeCreate(t *testing.T) {
+	volumes := map[string]map[string]string{"existing": nil}
+	d := newTestEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/volumes/"):
+			if _, ok := volumes[strings.TrimPrefix(r.URL.Path, "/volumes/")]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/volumes/create":
+			body := struct {
+				Name   string
+				Labels map[string]string
+			}{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			volumes[body.Name] = body.Labels
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	require.NoError(t, d.VolumeCreate("existing", map[string]string{"foo": "bar"}))
+	require.NoError(t, d.VolumeCreate("npm-cache", map[string]string{"foo": "bar"}))
+	assert.Equal(t, map[string]map[string]string{"existing": nil, "npm-cache": {"foo": "bar"}}, volumes)
+}
+
+func TestDockerAPIImagePullError(t *testing.T) {
+	d := newTestEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"pull access denied"}` + "\n"))
+	}))
+	err := d.ImagePull("whalebrew/jq")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pull access denied")
+}
+
+func TestDockerAPIRun(t *testing.T) {
+	var mu sync.Mutex
+	calls := []string{}
+	d := newTestEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/containers/create":
+			body := map[string]interface{}{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "alpine", body["Image"])
+			assert.Equal(t, []interface{}{"sh"}, body["Entrypoint"])
+			assert.Equal(t, []interface{}{"-c", "exit 3"}, body["Cmd"])
+			assert.Equal(t, "2048:4086", body["User"])
+			hostConfig := body["HostConfig"].(map[string]interface{})
+			assert.Equal(t, []interface{}{"/tmp:/tmp"}, hostConfig["Binds"])
+			assert.Equal(t, "default", hostConfig["NetworkMode"])
+			assert.Contains(t, hostConfig["PortBindings"], "80/tcp")
+			assert.Equal(t, true, hostConfig["AutoRemove"])
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"abc"}`))
+		case "/containers/abc/attach":
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			frames := &bytes.Buffer{}
+			writeFrame(frames, 1, "hello\n")
+			writeFrame(frames, 2, "oops\n")
+			buf.Write(frames.Bytes())
+			buf.Flush()
+		case "/containers/abc/start", "/containers/abc":
+			w.WriteHeader(http.StatusNoContent)
+		case "/containers/abc/wait":
+			assert.Equal(t, "removed", r.URL.Query().Get("condition"))
+			w.Write([]byte(`{"StatusCode":3}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	err := d.Run(&run.Execution{
+		Image:      "alpine",
+		Entrypoint: []string{"sh", "-c"},
+		Args:       []string{"exit 3"},
+		Ports:      []string{"8080:80"},
+		Networks:   []string{"default"},
+		Volumes:    []string{"/tmp:/tmp"},
+		WorkingDir: "/workdir",
+		User:       &user.User{Uid: "2048", Gid: "4086"},
+	})
+	assert.Equal(t, run.ExitError{Code: 3}, err)
+	assert.Equal(t, "hello\n", d.Stdout.(*bytes.Buffer).String())
+	assert.Equal(t, "oops\n", d.Stderr.(*bytes.Buffer).String())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"POST /containers/create",
+		"POST /containers/abc/attach",
+		"POST /containers/abc/wait",
+		"POST /containers/abc/start",
+		"DELETE /containers/abc",
+	}, calls)
+}
+
+func TestDockerAPIRunForwardsSignals(t *testing.T) {
+	killed := make(chan string, 1)
+	d := newTestEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/create":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"abc"}`))
+		case "/containers/abc/attach":
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+		case "/containers/abc/start":
+			syscall.Kill(os.Getpid(), syscall.SIGINT)
+			w.WriteHeader(http.StatusNoContent)
+		case "/containers/abc/kill":
+			killed <- r.URL.Query().Get("signal")
+			w.WriteHeader(http.StatusNoContent)
+		case "/containers/abc/wait":
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			select {
+			case signal := <-killed:
+				assert.Equal(t, "2", signal)
+				w.Write([]byte(`{"StatusCode":130}`))
+			case <-time.After(5 * time.Second):
+				w.Write([]byte(`{"StatusCode":0}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	err := d.Run(&run.Execution{Image: "alpine"})
+	assert.Equal(t, run.ExitError{Code: 130}, err)
+}
+
+func TestDockerAPIRunErrors(t *testing.T) {
+	d := newTestEngine(t, http.NotFoundHandler())
+	assert.Error(t, d.Run(nil))
+	assert.Error(t, d.Run(&run.Execution{}))
+	assert.Error(t, d.Run(&run.Execution{Image: "alpine", Ports: []string{"not-a-port"}}))
+}
+
+func TestDockerAPIPing(t *testing.T) {
+	answers := true
+	d := newTestEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_ping", r.URL.Path)
+		if !answers {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	assert.NoError(t, d.Ping())
+	answers = false
+	assert.Error(t, d.Ping())
+}
@@ -3,26 +3,52 @@ package run
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 
 	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
+const (
+	// FlavourDocker is the flavour of the docker command line
+	FlavourDocker = "docker"
+	// FlavourPodman is the flavour of the podman command line
+	FlavourPodman = "podman"
+	// FlavourNerdctl is the flavour of the nerdctl command line
+	FlavourNerdctl = "nerdctl"
+)
+
 // Docker implements the Runner interface
 type Docker struct {
-	Path       string
-	Exec       func(argv0 string, argv []string, envv []string) (err error)
-	RunCommand func(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error)
+	Path string
+	// Flavour is the docker like command line Path points to, defaults to FlavourDocker
+	Flavour string
+	// Args are global arguments provided to every command
+	Args []string
+	Exec func(argv0 string, argv []string, envv []string) (err error)
+	// ExecAndWait runs the command like Exec, but waits for it to complete instead of replacing the current process
+	ExecAndWait func(argv0 string, argv []string, envv []string) (err error)
+	RunCommand  func(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error)
 }
 
 var (
 	_          Runner         = &Docker{}
 	_          ImageInspecter = &Docker{}
+	_          ImagePuller    = &Docker{}
+	_          ImageDigester  = &Docker{}
+	_          VolumeCreator  = &Docker{}
+	_          Pinger         = &Docker{}
 	candidates                = []string{"docker", "podman"}
+	flavours                  = []string{FlavourDocker, FlavourPodman, FlavourNerdctl}
 )
 
 func RunComand(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error) {
@@ -33,38 +59,130 @@ func RunComand(argv0 string, argv []string, envv []string, stdout io.Writer, std
 	return c.Run()
 }
 
+// ExecAndWait runs argv with the standard input and outputs of the current process and waits for it to complete.
+// Like with Exec, argv includes the command name.
+// When the command exits with a non zero status, an ExitError is returned.
+// Termination signals are forwarded to the command, interrupts already reach it from the terminal.
+func ExecAndWait(argv0 string, argv []string, envv []string) error {
+	c := exec.Command(argv0, argv[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = envv
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	if err := c.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case s := <-signals:
+				if s != os.Interrupt {
+					c.Process.Signal(s)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := c.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return ExitError{Code: exitErr.ExitCode()}
+	}
+	return err
+}
+
 // NewDockerLikeRunner creates a new default Docker runner
 func NewDockerLikeRunner() (*Docker, error) {
+	return NewDockerLikeRunnerFor("", "", nil)
+}
+
+// NewDockerLikeRunnerFor creates a Docker runner using the given runtime.
+// When path is empty, the executable named after the flavour is used, or the first of docker and podman found.
+// When flavour is empty, it is guessed from the executable name.
+func NewDockerLikeRunnerFor(path, flavour string, args []string) (*Docker, error) {
+	if flavour != "" && !isFlavour(flavour) {
+		return nil, fmt.Errorf("unsupported runtime flavour %s, expecting one of %v", flavour, flavours)
+	}
 	var err error
 	var dockerPath string
 
-	for _, candidate := range candidates {
-		dockerPath, err = exec.LookPath(candidate)
-		if err == nil {
-			break
+	switch {
+	case path != "":
+		dockerPath, err = exec.LookPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find runtime %s: %w", path, err)
+		}
+	case flavour != "":
+		dockerPath, err = exec.LookPath(flavour)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find runtime %s: %w", flavour, err)
+		}
+	default:
+		for _, candidate := range candidates {
+			dockerPath, err = exec.LookPath(candidate)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to find any of %v executables: %w", candidates, err)
 		}
 	}
-	if err != nil {
-		return nil, fmt.Errorf("unable to find any of %v executables: %w", candidates, err)
+	if flavour == "" {
+		flavour = flavourOf(dockerPath)
 	}
 	return &Docker{
-		Path:       dockerPath,
-		Exec:       syscall.Exec,
-		RunCommand: RunComand,
+		Path:        dockerPath,
+		Flavour:     flavour,
+		Args:        args,
+		Exec:        syscall.Exec,
+		ExecAndWait: ExecAndWait,
+		RunCommand:  RunComand,
 	}, nil
 }
 
+func isFlavour(flavour string) bool {
+	for _, f := range flavours {
+		if f == flavour {
+			return true
+		}
+	}
+	return false
+}
+
+// flavourOf guesses the flavour of a docker like executable from its name
+func flavourOf(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if isFlavour(name) {
+		return name
+	}
+	return FlavourDocker
+}
+
+// command prefixes the arguments of a command with the global arguments
+func (d *Docker) command(args ...string) []string {
+	return append(append([]string{}, d.Args...), args...)
+}
+
 func (d *Docker) ImageInspect(imageName string) (*imagev1.Image, error) {
 	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
-	err := d.RunCommand(d.Path, []string{"image", "inspect", imageName}, os.Environ(), stdout, stderr)
+	err := d.RunCommand(d.Path, d.command("image", "inspect", imageName), os.Environ(), stdout, stderr)
 	if err != nil {
-		err = d.RunCommand(d.Path, []string{"image", "pull", imageName}, os.Environ(), os.Stdout, os.Stderr)
+		err = d.ImagePull(imageName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to download image %s: %w", imageName, err)
+			return nil, err
 		}
 		stdout.Reset()
 		stderr.Reset()
-		err = d.RunCommand(d.Path, []string{"image", "inspect", imageName}, os.Environ(), stdout, stderr)
+		err = d.RunCommand(d.Path, d.command("image", "inspect", imageName), os.Environ(), stdout, stderr)
 	}
 
 	if err != nil {
@@ -90,7 +208,64 @@ func (d *Docker) ImageInspect(imageName string) (*imagev1.Image, error) {
 	return &images[0], nil
 }
 
-// Run runs a given package until completion
+// ImagePull pulls the image from its registry, even if it is already present locally
+func (d *Docker) ImagePull(imageName string) error {
+	err := d.RunCommand(d.Path, d.command("image", "pull", imageName), os.Environ(), os.Stdout, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("failed to download image %s: %w", imageName, err)
+	}
+	return nil
+}
+
+// ImageRepoDigests returns the repository digests of a local image, without pulling it
+func (d *Docker) ImageRepoDigests(imageName string) ([]string, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err := d.RunCommand(d.Path, d.command("image", "inspect", "--format", "{{json .RepoDigests}}", imageName), os.Environ(), stdout, stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %w: %s", imageName, err, stderr.String())
+	}
+	digests := []string{}
+	err = json.NewDecoder(stdout).Decode(&digests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode digests of image %s: %w", imageName, err)
+	}
+	return digests, nil
+}
+
+// VolumeCreate creates the named volume with the given labels when it does not exist
+func (d *Docker) VolumeCreate(name string, labels map[string]string) error {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := d.RunCommand(d.Path, d.command("volume", "inspect", name), os.Environ(), stdout, stderr); err == nil {
+		return nil
+	}
+	args := []string{"volume", "create"}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+	stdout.Reset()
+	stderr.Reset()
+	if err := d.RunCommand(d.Path, d.command(append(args, name)...), os.Environ(), stdout, stderr); err != nil {
+		return fmt.Errorf("failed to create volume %s: %w: %s", name, err, stderr.String())
+	}
+	return nil
+}
+
+// Ping checks the engine behind the command line answers
+func (d *Docker) Ping() error {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := d.RunCommand(d.Path, d.command("info"), os.Environ(), stdout, stderr); err != nil {
+		return fmt.Errorf("%s does not answer: %w: %s", d.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Run runs a given package until completion.
+// The docker command replaces the current process, unless the execution must be waited for.
 func (d *Docker) Run(e *Execution) error {
 	if e == nil {
 		return fmt.Errorf("no execution provided")
@@ -98,14 +273,13 @@ func (d *Docker) Run(e *Execution) error {
 	if e.Image == "" {
 		return fmt.Errorf("no image to run")
 	}
-	dockerArgs := []string{
-		d.Path,
+	dockerArgs := append([]string{d.Path}, d.command(
 		"run",
 		"--interactive",
 		"--rm",
 		"--workdir", e.WorkingDir,
 		"--init",
-	}
+	)...)
 	args := e.Args
 	if e.Entrypoint != nil {
 		if len(e.Entrypoint) > 0 {
@@ -135,16 +309,42 @@ func (d *Docker) Run(e *Execution) error {
 		dockerArgs = append(dockerArgs, "-v")
 		dockerArgs = append(dockerArgs, volume)
 	}
+	if e.CPUs != "" {
+		dockerArgs = append(dockerArgs, "--cpus", e.CPUs)
+	}
+	if e.Memory != "" {
+		dockerArgs = append(dockerArgs, "--memory", e.Memory)
+	}
+	if e.PidsLimit != 0 {
+		dockerArgs = append(dockerArgs, "--pids-limit", strconv.FormatInt(e.PidsLimit, 10))
+	}
+	if e.ReadOnly {
+		dockerArgs = append(dockerArgs, "--read-only")
+	}
+	for _, capability := range e.CapDrop {
+		dockerArgs = append(dockerArgs, "--cap-drop", capability)
+	}
+	if e.NoNewPrivileges {
+		dockerArgs = append(dockerArgs, "--security-opt", "no-new-privileges")
+	}
 	if !e.KeepContainerUser {
-		if e.User != nil {
+		if d.Flavour == FlavourPodman && e.User != nil && e.User.Uid != "0" {
+			// rootless podman maps the current user to the same id in the container
+			// keeping files created on volumes owned by the user
+			dockerArgs = append(dockerArgs, "--userns=keep-id")
+		} else if e.User != nil {
 			dockerArgs = append(dockerArgs, "-u")
 			dockerArgs = append(dockerArgs, e.User.Uid+":"+e.User.Gid)
 		}
 	}
 	dockerArgs = append(dockerArgs, e.Image)
 	dockerArgs = append(dockerArgs, args...)
-	if d.Exec == nil {
+	execute := d.Exec
+	if e.Wait {
+		execute = d.ExecAndWait
+	}
+	if execute == nil {
 		return fmt.Errorf("no docker executable provided")
 	}
-	return d.Exec(d.Path, dockerArgs, os.Environ())
+	return execute(d.Path, dockerArgs, os.Environ())
 }
@@ -2,9 +2,11 @@ package run_test
 
 import (
 	"errors"
+	"io"
 	"os"
 	"os/exec"
 	"os/user"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -60,6 +62,15 @@ func TestDockerRun(t *testing.T) {
 	assert.NoError(t, d.Run(&run.Execution{
 		Image: "alpine",
 	}))
+	assert.Error(t, d.Run(&run.Execution{
+		Image: "alpine",
+		Wait:  true,
+	}))
+	d.ExecAndWait = func(argv0 string, argv []string, envv []string) (err error) { return run.ExitError{Code: 3} }
+	assert.Equal(t, run.ExitError{Code: 3}, d.Run(&run.Execution{
+		Image: "alpine",
+		Wait:  true,
+	}))
 
 	d.Exec = func(argv0 string, argv []string, envv []string) (err error) {
 		assert.Equal(t, "docker", argv0)
@@ -124,3 +135,188 @@ func TestDockerRun(t *testing.T) {
 		},
 	}))
 }
+
+func TestDockerImageRepoDigests(t *testing.T) {
+	d := run.Docker{
+		Path: "docker",
+		RunCommand: func(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error) {
+			assert.Equal(t, "docker", argv0)
+			assert.Equal(t, []string{"image", "inspect", "--format", "{{json .RepoDigests}}", "whalebrew/jq"}, argv)
+			_, err = stdout.Write([]byte(`["whalebrew/jq@sha256:1234"]` + "\n"))
+			return err
+		},
+	}
+	digests, err := d.ImageRepoDigests("whalebrew/jq")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"whalebrew/jq@sha256:1234"}, digests)
+
+	d.RunCommand = func(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error) {
+		return errors.New("no such image")
+	}
+	_, err = d.ImageRepoDigests("whalebrew/jq")
+	assert.Error(t, err)
+}
+
+func TestDockerRunWithRuntime(t *testing.T) {
+	execution := &run.Execution{
+		Image:      "alpine",
+		WorkingDir: "/workdir",
+		User: &user.User{
+			Uid: "2048",
+			Gid: "4086",
+		},
+	}
+	t.Run("with global arguments", func(t *testing.T) {
+		d := run.Docker{
+			Path: "docker",
+			Args: []string{"--context", "remote"},
+			Exec: func(argv0 string, argv []string, envv []string) (err error) {
+				assert.Equal(t, []string{"docker", "--context", "remote", "run", "--interactive", "--rm", "--workdir", "/workdir", "--init", "-u", "2048:4086", "alpine"}, argv)
+				return nil
+			},
+		}
+		assert.NoError(t, d.Run(execution))
+	})
+	t.Run("with podman", func(t *testing.T) {
+		d := run.Docker{
+			Path:    "podman",
+			Flavour: run.FlavourPodman,
+			Exec: func(argv0 string, argv []string, envv []string) (err error) {
+				assert.Equal(t, []string{"podman", "run", "--interactive", "--rm", "--workdir", "/workdir", "--init", "--userns=keep-id", "alpine"}, argv)
+				return nil
+			},
+		}
+		assert.NoError(t, d.Run(execution))
+	})
+	t.Run("with podman run as root", func(t *testing.T) {
+		d := run.Docker{
+			Path:    "podman",
+			Flavour: run.FlavourPodman,
+			Exec: func(argv0 string, argv []string, envv []string) (err error) {
+				assert.Equal(t, []string{"podman", "run", "--interactive", "--rm", "--workdir", "/workdir", "--init", "-u", "0:0", "alpine"}, argv)
+				return nil
+			},
+		}
+		assert.NoError(t, d.Run(&run.Execution{Image: "alpine", WorkingDir: "/workdir", User: &user.User{Uid: "0", Gid: "0"}}))
+	})
+}
+
+func TestNewDockerLikeRunnerFor(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"podman", "nerdctl", "my-docker"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755))
+	}
+	t.Setenv("PATH", dir)
+
+	d, err := run.NewDockerLikeRunnerFor("nerdctl", "", []string{"--namespace", "k8s.io"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "nerdctl"), d.Path)
+	assert.Equal(t, run.FlavourNerdctl, d.Flavour)
+	assert.Equal(t, []string{"--namespace", "k8s.io"}, d.Args)
+
+	d, err = run.NewDockerLikeRunnerFor(filepath.Join(dir, "my-docker"), "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, run.FlavourDocker, d.Flavour)
+
+	d, err = run.NewDockerLikeRunnerFor(filepath.Join(dir, "my-docker"), run.FlavourPodman, nil)
+	require.NoError(t, err)
+	assert.Equal(t, run.FlavourPodman, d.Flavour)
+
+	d, err = run.NewDockerLikeRunnerFor("", run.FlavourPodman, nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "podman"), d.Path)
+
+	d, err = run.NewDockerLikeRunnerFor("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "podman"), d.Path)
+	assert.Equal(t, run.FlavourPodman, d.Flavour)
+
+	_, err = run.NewDockerLikeRunnerFor("", "containerd", nil)
+	assert.Error(t, err)
+	_, err = run.NewDockerLikeRunnerFor("does-not-exist", "", nil)
+	assert.Error(t, err)
+}
+
+func TestDockerRunWithLimits(t *testing.T) {
+	d := run.Docker{
+		Path: "docker",
+		Exec: func(argv0 string, argv []string, envv []string) (err error) {
+			assert.Equal(
+				t,
+				[]string{
+					"docker", "run", "--interactive", "--rm",
+					"--workdir", "/workdir", "--init",
+					"--cpus", "1.5", "--memory", "512m", "--pids-limit", "100",
+					"--read-only", "--cap-drop", "ALL", "--cap-drop", "NET_RAW",
+					"--security-opt", "no-new-privileges",
+					"alpine",
+				},
+				argv,
+			)
+			return nil
+		},
+	}
+	assert.NoError(t, d.Run(&run.Execution{
+		Image:             "alpine",
+		WorkingDir:        "/workdir",
+		KeepContainerUser: true,
+		CPUs:              "1.5",
+		Memory:            "512m",
+		PidsLimit:         100,
+		ReadOnly:          true,
+		CapDrop:           []string{"ALL", "NET_RAW"},
+		NoNewPrivileges:   true,
+	}))
+}
+
+func TestDockerVolumeCreate(t *testing.T) {
+	calls := [][]string{}
+	d := run.Docker{
+		Path: "docker",
+		RunCommand: func(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error) {
+			calls = append(calls, argv)
+			if argv[1] == "inspect" {
+				return errors.New("no such volume")
+			}
+			return nil
+		},
+	}
+	require.NoError(t, d.VolumeCreate("npm-cache", map[string]string{"io.whalebrew.image": "whalebrew/npm", "a": "b"}))
+	assert.Equal(t, [][]string{
+		{"volume", "inspect", "npm-cache"},
+		{"volume", "create", "--label", "a=b", "--label", "io.whalebrew.image=whalebrew/npm", "npm-cache"},
+	}, calls)
+
+	calls = [][]string{}
+	d.RunCommand = func(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error) {
+		calls = append(calls, argv)
+		return nil
+	}
+	require.NoError(t, d.VolumeCreate("npm-cache", nil))
+	assert.Equal(t, [][]string{{"volume", "inspect", "npm-cache"}}, calls)
+}
+
+func TestDockerPing(t *testing.T) {
+	d := run.Docker{
+		Path: "docker",
+		Args: []string{"--context", "remote"},
+		RunCommand: func(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error) {
+			assert.Equal(t, []string{"--context", "remote", "info"}, argv)
+			return nil
+		},
+	}
+	assert.NoError(t, d.Ping())
+	d.RunCommand = func(argv0 string, argv []string, envv []string, stdout io.Writer, stderr io.Writer) (err error) {
+		stderr.Write([]byte("Cannot connect to the Docker daemon\n"))
+		return errors.New("exit status 1")
+	}
+	err := d.Ping()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Cannot connect to the Docker daemon")
+}
+
+func TestExecAndWait(t *testing.T) {
+	assert.NoError(t, run.ExecAndWait("true", []string{"true"}, os.Environ()))
+	assert.Equal(t, run.ExitError{Code: 3}, run.ExecAndWait("sh", []string{"sh", "-c", "exit 3"}, os.Environ()))
+	assert.Error(t, run.ExecAndWait("/does/not/exist", []string{"/does/not/exist"}, os.Environ()))
+}
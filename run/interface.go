@@ -1,6 +1,7 @@
 package run
 
 import (
+	"fmt"
 	"os/user"
 
 	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -8,17 +9,31 @@ import (
 
 // Execution defunes elements that depends on the current runtime request
 type Execution struct {
-	Image             string
-	Entrypoint        []string
-	Ports             []string
-	Networks          []string
-	KeepContainerUser bool
-	Environment       []string
-	IsTTYOpened       bool
-	Args              []string
-	User              *user.User
-	WorkingDir        string
-	Volumes           []string
+	Image             string     `json:"image"`
+	Entrypoint        []string   `json:"entrypoint,omitempty"`
+	Ports             []string   `json:"ports,omitempty"`
+	Networks          []string   `json:"networks,omitempty"`
+	KeepContainerUser bool       `json:"keep_container_user,omitempty"`
+	Environment       []string   `json:"environment,omitempty"`
+	IsTTYOpened       bool       `json:"tty,omitempty"`
+	Args              []string   `json:"args,omitempty"`
+	User              *user.User `json:"user,omitempty"`
+	WorkingDir        string     `json:"working_dir,omitempty"`
+	Volumes           []string   `json:"volumes,omitempty"`
+	// Runtime is the docker like command line requested by the package, if any
+	Runtime string `json:"runtime,omitempty"`
+	// CPUs limits the number of CPUs the container can use, like 1.5
+	CPUs string `json:"cpus,omitempty"`
+	// Memory limits the memory the container can use, like 512m
+	Memory    string `json:"memory,omitempty"`
+	PidsLimit int64  `json:"pids_limit,omitempty"`
+	ReadOnly  bool   `json:"read_only,omitempty"`
+	// CapDrop lists the linux capabilities to drop from the container
+	CapDrop         []string `json:"cap_drop,omitempty"`
+	NoNewPrivileges bool     `json:"no_new_privileges,omitempty"`
+	// Wait waits for the command to complete and returns an ExitError when it fails,
+	// instead of replacing the current process when the runner is able to
+	Wait bool `json:"-"`
 }
 
 // Runner must run until compoletion and return an error wether something failed
@@ -29,3 +44,41 @@ type Runner interface {
 type ImageInspecter interface {
 	ImageInspect(imageName string) (*imagev1.Image, error)
 }
+
+// ImagePuller fetches the latest version of an image from its registry
+type ImagePuller interface {
+	ImagePull(imageName string) error
+}
+
+// ImageDigester lists the registry digests of an image available locally
+type ImageDigester interface {
+	ImageRepoDigests(imageName string) ([]string, error)
+}
+
+// VolumeCreator creates named volumes, doing nothing when the volume already exists
+type VolumeCreator interface {
+	VolumeCreate(name string, labels map[string]string) error
+}
+
+// Pinger checks the container engine answers
+type Pinger interface {
+	Ping() error
+}
+
+// Engine groups the features whalebrew needs from a container engine
+type Engine interface {
+	Runner
+	ImageInspecter
+	ImagePuller
+	ImageDigester
+	VolumeCreator
+}
+
+// ExitError is returned by runners waiting for the command to complete when it exits with a non zero status
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
@@ -0,0 +1,43 @@
+package run
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var memoryPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?) ?([kKmMgGtT])?[iI]?[bB]?$`)
+
+// ParseCPUs parses a CPU limit expressed as a number of CPUs, like 1.5
+func ParseCPUs(cpus string) (float64, error) {
+	value, err := strconv.ParseFloat(cpus, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid CPU limit %q, expecting a positive number of CPUs", cpus)
+	}
+	return value, nil
+}
+
+// ParseMemory parses a memory limit like 512m or 1g into bytes, units being powers of 1024
+func ParseMemory(memory string) (int64, error) {
+	matches := memoryPattern.FindStringSubmatch(memory)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid memory limit %q, expecting a size like 512m or 1g", memory)
+	}
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory limit %q: %w", memory, err)
+	}
+	multiplier := int64(1)
+	switch strings.ToLower(matches[2]) {
+	case "k":
+		multiplier = 1 << 10
+	case "m":
+		multiplier = 1 << 20
+	case "g":
+		multiplier = 1 << 30
+	case "t":
+		multiplier = 1 << 40
+	}
+	return int64(value * float64(multiplier)), nil
+}
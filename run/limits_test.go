@@ -0,0 +1,37 @@
+package run_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+func TestParseCPUs(t *testing.T) {
+	cpus, err := run.ParseCPUs("1.5")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, cpus)
+	for _, invalid := range []string{"", "0", "-1", "two"} {
+		_, err := run.ParseCPUs(invalid)
+		assert.Errorf(t, err, "parsing %q should fail", invalid)
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"1024": 1024,
+		"10b":  10,
+		"2k":   2048,
+		"512m": 512 * 1024 * 1024,
+		"1.5G": 1536 * 1024 * 1024,
+		"1GiB": 1024 * 1024 * 1024,
+	} {
+		memory, err := run.ParseMemory(value)
+		assert.NoError(t, err)
+		assert.Equalf(t, expected, memory, "parsing %q", value)
+	}
+	for _, invalid := range []string{"", "m", "12x", "-1g"} {
+		_, err := run.ParseMemory(invalid)
+		assert.Errorf(t, err, "parsing %q should fail", invalid)
+	}
+}
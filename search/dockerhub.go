@@ -1,60 +1,149 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
+
+	"github.com/whalebrew/whalebrew/dockerregistry"
 )
 
+const dockerHubURL = "https://hub.docker.com"
+
+// maxTagPages bounds the pages of tags listed for an image, images with more tags only list the most recently updated ones
+const maxTagPages = 10
+
 // DockerHub implements the Searcher interface and searches for images with a specific owner
 type DockerHub struct {
 	Owner string
+	// URL is the URL of the docker hub API, defaults to https://hub.docker.com
+	URL string
 }
 
 type imageResult struct {
-	Namespace string `json:"namespace"`
-	Name      string `json:"name"`
+	Namespace   string     `json:"namespace"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	LastUpdated *time.Time `json:"last_updated"`
+	StarCount   int        `json:"star_count"`
+	PullCount   int64      `json:"pull_count"`
 }
 type searchAnswer struct {
+	Next    string        `json:"next"`
 	Results []imageResult `json:"results"`
 }
 
-func (dh *DockerHub) Search(term string, handleError ErrorHandler) <-chan string {
-	out := make(chan string)
+type tagResult struct {
+	Name        string     `json:"name"`
+	LastUpdated *time.Time `json:"last_updated"`
+	Digest      string     `json:"digest"`
+}
+type tagsAnswer struct {
+	Next    string      `json:"next"`
+	Results []tagResult `json:"results"`
+}
+
+func (dh *DockerHub) url(path string, params url.Values) string {
+	base := dh.URL
+	if base == "" {
+		base = dockerHubURL
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		u = &url.URL{Scheme: "https", Host: "hub.docker.com"}
+	}
+	u.Path = path
+	u.RawQuery = params.Encode()
+	return u.String()
+}
+
+func get(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status %d, expecting %d", r.StatusCode, http.StatusOK)
+	}
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+func (dh *DockerHub) String() string {
+	return "docker hub " + dh.Owner
+}
+
+// Search searches the images of the owner, following the pages of results
+func (dh *DockerHub) Search(ctx context.Context, term string, handleError ErrorHandler) <-chan Result {
+	out := make(chan Result)
 	if handleError == nil {
 		handleError = defaultErrorHandler
 	}
 	go func() {
+		defer close(out)
 		params := url.Values{}
 		params.Set("page_size", "100")
 		params.Set("ordering", "last_updated")
 		params.Set("name", term)
-		u := url.URL{
-			Scheme:   "https",
-			Host:     "hub.docker.com",
-			Path:     fmt.Sprintf("/v2/repositories/%s/", dh.Owner),
-			RawQuery: params.Encode(),
-		}
-		r, err := http.Get(u.String())
-		if err != nil {
-			if handleError(err) {
-				close(out)
+		next := dh.url(fmt.Sprintf("/v2/repositories/%s/", dh.Owner), params)
+		for next != "" {
+			answer := searchAnswer{}
+			if err := get(ctx, next, &answer); err != nil {
+				handleError(err)
 				return
 			}
-		}
-		answer := searchAnswer{}
-		err = json.NewDecoder(r.Body).Decode(&answer)
-		if err != nil {
-			if handleError(err) {
-				close(out)
-				return
+			for _, image := range answer.Results {
+				result := Result{
+					Image:       fmt.Sprintf("%s/%s", image.Namespace, image.Name),
+					Description: image.Description,
+					LastUpdated: image.LastUpdated,
+					Stars:       image.StarCount,
+					Pulls:       image.PullCount,
+				}
+				select {
+				case out <- result:
+				case <-ctx.Done():
+					handleError(ctx.Err())
+					return
+				}
 			}
+			next = answer.Next
 		}
-		for _, image := range answer.Results {
-			out <- fmt.Sprintf("%s/%s", image.Namespace, image.Name)
-		}
-		close(out)
 	}()
 	return out
 }
+
+// Tags lists the tags of an image hosted on docker hub, the most recently updated first.
+// At most maxTagPages pages of tags are listed.
+func (dh *DockerHub) Tags(ctx context.Context, image string) ([]Tag, error) {
+	ref, err := dockerregistry.ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	if !ref.IsDockerHub() {
+		return nil, fmt.Errorf("%s is not hosted on docker hub", image)
+	}
+	params := url.Values{}
+	params.Set("page_size", "100")
+	params.Set("ordering", "last_updated")
+	next := dh.url(fmt.Sprintf("/v2/repositories/%s/tags", ref.Path), params)
+	tags := []Tag{}
+	for page := 0; next != "" && page < maxTagPages; page++ {
+		answer := tagsAnswer{}
+		if err := get(ctx, next, &answer); err != nil {
+			return nil, err
+		}
+		for _, tag := range answer.Results {
+			tags = append(tags, Tag{Name: tag.Name, LastUpdated: tag.LastUpdated, Digest: tag.Digest})
+		}
+		next = answer.Next
+	}
+	return tags, nil
+}
@@ -1,24 +1,123 @@
 package search
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDockerHub(t *testing.T) {
 	d := DockerHub{Owner: "whalebrew"}
 	count := 0
-	for img := range d.Search("jq", nil) {
-		assert.Equal(t, "whalebrew/jq", img)
+	for result := range d.Search(context.Background(), "jq", nil) {
+		assert.Equal(t, "whalebrew/jq", result.Image)
 		count++
 	}
 	assert.Equal(t, 1, count)
 	d = DockerHub{Owner: "bitnami"}
 	count = 0
-	for img := range d.Search("kubectl", nil) {
-		assert.Equal(t, "bitnami/kubectl", img)
+	for result := range d.Search(context.Background(), "kubectl", nil) {
+		assert.Equal(t, "bitnami/kubectl", result.Image)
 		count++
 	}
 	assert.Equal(t, 1, count)
 }
+
+func newTestDockerHub(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDockerHubDetails(t *testing.T) {
+	server := newTestDockerHub(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/repositories/whalebrew/", r.URL.Path)
+		assert.Equal(t, "jq", r.URL.Query().Get("name"))
+		fmt.Fprint(w, `{"results":[{"namespace":"whalebrew","name":"jq","description":"Command-line JSON processor","last_updated":"2021-03-04T05:06:07Z","star_count":3,"pull_count":12345}]}`)
+	})
+	d := DockerHub{Owner: "whalebrew", URL: server.URL}
+	updated := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	results := []Result{}
+	for result := range d.Search(context.Background(), "jq", nil) {
+		results = append(results, result)
+	}
+	assert.Equal(t, []Result{{
+		Image:       "whalebrew/jq",
+		Description: "Command-line JSON processor",
+		LastUpdated: &updated,
+		Stars:       3,
+		Pulls:       12345,
+	}}, results)
+}
+
+func TestDockerHubSearchPages(t *testing.T) {
+	var server *httptest.Server
+	server = newTestDockerHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `{"next":"%s/v2/repositories/whalebrew/?name=j&page=2","results":[{"namespace":"whalebrew","name":"jq"}]}`, server.URL)
+		case "2":
+			fmt.Fprint(w, `{"results":[{"namespace":"whalebrew","name":"jo"}]}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	})
+	d := DockerHub{Owner: "whalebrew", URL: server.URL}
+	images := []string{}
+	for result := range d.Search(context.Background(), "j", nil) {
+		images = append(images, result.Image)
+	}
+	assert.Equal(t, []string{"whalebrew/jq", "whalebrew/jo"}, images)
+}
+
+func TestDockerHubTags(t *testing.T) {
+	var server *httptest.Server
+	server = newTestDockerHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/v2/repositories/library/alpine/tags":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Query().Get("page") == "":
+			fmt.Fprintf(w, `{"next":"%s/v2/repositories/library/alpine/tags?page=2","results":[{"name":"latest","last_updated":"2021-03-04T05:06:07Z","digest":"sha256:1234"}]}`, server.URL)
+		default:
+			fmt.Fprint(w, `{"next":null,"results":[{"name":"3.13","digest":"sha256:5678"}]}`)
+		}
+	})
+	d := DockerHub{URL: server.URL}
+	updated := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	tags, err := d.Tags(context.Background(), "alpine:3.13")
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{
+		{Name: "latest", LastUpdated: &updated, Digest: "sha256:1234"},
+		{Name: "3.13", Digest: "sha256:5678"},
+	}, tags)
+
+	_, err = d.Tags(context.Background(), "whalebrew/missing")
+	assert.Error(t, err)
+	_, err = d.Tags(context.Background(), "quay.io/some/image")
+	assert.Error(t, err)
+}
+
+func TestDockerHubTagsPages(t *testing.T) {
+	requests := 0
+	var server *httptest.Server
+	server = newTestDockerHub(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"next":"%s/v2/repositories/library/alpine/tags?page=%d","results":[{"name":"%d"}]}`, server.URL, requests+1, requests)
+	})
+	d := DockerHub{URL: server.URL}
+	tags, err := d.Tags(context.Background(), "alpine")
+	require.NoError(t, err)
+	assert.Len(t, tags, maxTagPages)
+	assert.Equal(t, maxTagPages, requests)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = d.Tags(ctx, "alpine")
+	assert.Error(t, err)
+}
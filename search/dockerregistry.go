@@ -1,6 +1,7 @@
 package search
 
 import (
+	"context"
 	"strings"
 
 	"github.com/whalebrew/whalebrew/dockerregistry"
@@ -13,29 +14,60 @@ type DockerRegistry struct {
 }
 
 type Cataloger interface {
-	Catalog() (dockerregistry.Catalog, error)
+	Catalog(ctx context.Context) (dockerregistry.Catalog, error)
+	Tags(ctx context.Context, name string) (dockerregistry.TagList, error)
 	ImageName(path string) string
 }
 
-func (dr *DockerRegistry) Search(term string, handleError ErrorHandler) <-chan string {
-	out := make(chan string)
+func (dr *DockerRegistry) String() string {
+	return "registry " + dr.Registry.ImageName(dr.Owner)
+}
+
+// Search searches the images of the owner in the catalog of the registry
+func (dr *DockerRegistry) Search(ctx context.Context, term string, handleError ErrorHandler) <-chan Result {
+	out := make(chan Result)
 	if handleError == nil {
 		handleError = defaultErrorHandler
 	}
 	go func() {
-		catalog, err := dr.Registry.Catalog()
-		if err != nil && handleError(err) {
-			close(out)
+		defer close(out)
+		catalog, err := dr.Registry.Catalog(ctx)
+		if err != nil {
+			handleError(err)
 			return
 		}
 		for _, repo := range catalog.Repositories {
 			if strings.HasPrefix(repo, dr.Owner+"/") {
 				if strings.Contains(strings.TrimPrefix(repo, dr.Owner+"/"), term) {
-					out <- dr.Registry.ImageName(repo)
+					select {
+					case out <- Result{Image: dr.Registry.ImageName(repo)}:
+					case <-ctx.Done():
+						handleError(ctx.Err())
+						return
+					}
 				}
 			}
 		}
-		close(out)
 	}()
 	return out
 }
+
+// Tags lists the tags of an image hosted in the registry
+func (dr *DockerRegistry) Tags(ctx context.Context, image string) ([]Tag, error) {
+	name := strings.TrimPrefix(image, dr.Registry.ImageName(""))
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	list, err := dr.Registry.Tags(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	tags := []Tag{}
+	for _, name := range list.Tags {
+		tags = append(tags, Tag{Name: name})
+	}
+	return tags, nil
+}
@@ -1,15 +1,18 @@
 package search
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/whalebrew/whalebrew/dockerregistry"
 )
 
 type fakeCatalog struct{}
 
-func (fakeCatalog) Catalog() (dockerregistry.Catalog, error) {
+func (fakeCatalog) Catalog(ctx context.Context) (dockerregistry.Catalog, error) {
 	return dockerregistry.Catalog{
 		Repositories: []string{
 			"some-folder/some-image",
@@ -20,6 +23,13 @@ func (fakeCatalog) Catalog() (dockerregistry.Catalog, error) {
 	}, nil
 }
 
+func (fakeCatalog) Tags(ctx context.Context, name string) (dockerregistry.TagList, error) {
+	if name != "some-folder/some-image" {
+		return dockerregistry.TagList{}, errors.New("not found")
+	}
+	return dockerregistry.TagList{Name: name, Tags: []string{"latest", "1.0"}}, nil
+}
+
 func (fakeCatalog) ImageName(path string) string {
 	return "my-registry/" + path
 }
@@ -30,9 +40,17 @@ func TestDockerRegistry(t *testing.T) {
 		Registry: fakeCatalog{},
 	}
 	count := 0
-	for img := range dr.Search("some", nil) {
-		assert.Equal(t, "my-registry/some-folder/some-image", img)
+	for result := range dr.Search(context.Background(), "some", nil) {
+		assert.Equal(t, Result{Image: "my-registry/some-folder/some-image"}, result)
 		count++
 	}
 	assert.Equal(t, 1, count)
+
+	for _, image := range []string{"my-registry/some-folder/some-image", "my-registry/some-folder/some-image:1.0", "my-registry/some-folder/some-image@sha256:1234"} {
+		tags, err := dr.Tags(context.Background(), image)
+		require.NoError(t, err)
+		assert.Equal(t, []Tag{{Name: "latest"}, {Name: "1.0"}}, tags)
+	}
+	_, err := dr.Tags(context.Background(), "my-registry/other-folder/other-image")
+	assert.Error(t, err)
 }
@@ -0,0 +1,257 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+const (
+	// IndexFileName is the name of the search index in the whalebrew configuration directory
+	IndexFileName = "search-index.json"
+	// DefaultIndexMaxAge is how long the index of a registry is used before searching the registry again
+	DefaultIndexMaxAge = 24 * time.Hour
+
+	whalebrewLabelPrefix = "io.whalebrew."
+)
+
+// IndexPath returns the path of the search index
+func IndexPath() string {
+	return filepath.Join(config.ConfigDir(), IndexFileName)
+}
+
+// Source is what a searcher found when it was last indexed
+type Source struct {
+	Searcher  string    `json:"searcher"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Results   []Result  `json:"results"`
+}
+
+// Index records the images of the searchers so they can be searched offline
+type Index struct {
+	Sources []Source `json:"sources"`
+	path    string
+}
+
+// LoadIndex reads the index at the given path. A missing index is considered empty.
+func LoadIndex(path string) (*Index, error) {
+	idx := &Index{Sources: []Source{}, path: path}
+	d, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(d, idx); err != nil {
+		return nil, fmt.Errorf("invalid search index %s: %v", path, err)
+	}
+	return idx, nil
+}
+
+// Save writes the index, replacing the previous version atomically
+func (idx *Index) Save() error {
+	d, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return err
+	}
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, d, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+// Source returns what the searcher found when it was last indexed
+func (idx *Index) Source(searcher Searcher) (Source, bool) {
+	name := fmt.Sprint(searcher)
+	for _, source := range idx.Sources {
+		if source.Searcher == name {
+			return source, true
+		}
+	}
+	return Source{}, false
+}
+
+func (idx *Index) set(source Source) {
+	for i := range idx.Sources {
+		if idx.Sources[i].Searcher == source.Searcher {
+			idx.Sources[i] = source
+			return
+		}
+	}
+	idx.Sources = append(idx.Sources, source)
+}
+
+// Refresh indexes all the images of the searcher.
+// When inspecter is not nil, the entrypoint and the whalebrew labels of each image are indexed too.
+// Images that can not be inspected are reported to handleError and indexed without labels.
+// ctx bounds both the listing and the inspection, images left to inspect once it is done are reported once.
+func (idx *Index) Refresh(ctx context.Context, searcher Searcher, inspecter run.ImageInspecter, handleError func(error)) (Source, error) {
+	var searchErr error
+	results := []Result{}
+	for result := range searcher.Search(ctx, "", func(err error) {
+		searchErr = err
+	}) {
+		results = append(results, result)
+	}
+	if searchErr != nil {
+		return Source{}, searchErr
+	}
+	if inspecter != nil {
+		results = inspect(ctx, inspecter, results, func(result *Result, imageInspect *imagev1.Image, err error) bool {
+			if err != nil && ctx.Err() != nil {
+				return true
+			}
+			if err != nil {
+				handleError(fmt.Errorf("%s: %w", result.Image, err))
+				return true
+			}
+			result.Entrypoint = imageInspect.Config.Entrypoint
+			for label, value := range imageInspect.Config.Labels {
+				if strings.HasPrefix(label, whalebrewLabelPrefix) {
+					if result.Labels == nil {
+						result.Labels = map[string]string{}
+					}
+					result.Labels[label] = value
+				}
+			}
+			return true
+		})
+		if err := ctx.Err(); err != nil {
+			handleError(fmt.Errorf("the remaining images of %v: %w", searcher, err))
+		}
+	}
+	source := Source{Searcher: fmt.Sprint(searcher), UpdatedAt: time.Now().UTC(), Results: results}
+	idx.set(source)
+	return source, nil
+}
+
+// Match returns the results matching term, the best matches first.
+// Matching is fuzzy: the letters of term must appear in order in the image name, or term must appear in the description.
+func Match(results []Result, term string) []Result {
+	type match struct {
+		result Result
+		score  int
+	}
+	matches := []match{}
+	for _, result := range results {
+		if score, ok := fuzzyScore(term, result); ok {
+			matches = append(matches, match{result, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score < matches[j].score
+	})
+	found := []Result{}
+	for _, m := range matches {
+		found = append(found, m.result)
+	}
+	return found
+}
+
+// fuzzyScore tells whether term matches the result and how well, lower scores being better matches
+func fuzzyScore(term string, result Result) (int, bool) {
+	term = strings.ToLower(term)
+	name := strings.ToLower(result.Image[strings.LastIndex(result.Image, "/")+1:])
+	switch {
+	case name == term:
+		return 0, true
+	case strings.HasPrefix(name, term):
+		return 1, true
+	case strings.Contains(name, term):
+		return 2, true
+	case isSubsequence(term, name):
+		return 3, true
+	case strings.Contains(strings.ToLower(result.Description), term):
+		return 4, true
+	}
+	return 0, false
+}
+
+// isSubsequence reports whether the letters of s appear in order in other
+func isSubsequence(s, other string) bool {
+	for _, c := range s {
+		i := strings.IndexRune(other, c)
+		if i < 0 {
+			return false
+		}
+		other = other[i+len(string(c)):]
+	}
+	return true
+}
+
+// Cached searches the index of a searcher while it is fresh, and the searcher itself otherwise.
+// When the searcher fails, its stale index is searched instead.
+type Cached struct {
+	Searcher Searcher
+	Index    *Index
+	MaxAge   time.Duration
+	// Offline only searches the index, whatever its age
+	Offline bool
+}
+
+func (c *Cached) String() string {
+	return fmt.Sprint(c.Searcher)
+}
+
+// Search searches the index or the searcher for images matching term
+func (c *Cached) Search(ctx context.Context, term string, handleError ErrorHandler) <-chan Result {
+	out := make(chan Result)
+	if handleError == nil {
+		handleError = defaultErrorHandler
+	}
+	go func() {
+		defer close(out)
+		source, indexed := c.Index.Source(c.Searcher)
+		results := []Result{}
+		switch {
+		case c.Offline && !indexed:
+			handleError(fmt.Errorf("not indexed yet, run whalebrew update-index"))
+			return
+		case c.Offline || (indexed && time.Since(source.UpdatedAt) <= c.MaxAge):
+			results = Match(source.Results, term)
+		default:
+			var searchErr error
+			for result := range c.Searcher.Search(ctx, term, func(err error) {
+				searchErr = err
+			}) {
+				results = append(results, result)
+			}
+			if searchErr != nil {
+				if !indexed {
+					handleError(searchErr)
+					return
+				}
+				handleError(fmt.Errorf("%w, using the index of %s", searchErr, source.UpdatedAt.Local().Format(time.RFC822)))
+				results = Match(source.Results, term)
+			}
+		}
+		for _, result := range results {
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				handleError(ctx.Err())
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Tags lists the tags of an image with the searcher
+func (c *Cached) Tags(ctx context.Context, image string) ([]Tag, error) {
+	return c.Searcher.Tags(ctx, image)
+}
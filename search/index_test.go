@@ -0,0 +1,146 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func images(results []Result) []string {
+	names := []string{}
+	for _, result := range results {
+		names = append(names, result.Image)
+	}
+	return names
+}
+
+func TestMatch(t *testing.T) {
+	results := []Result{
+		{Image: "whalebrew/kubectl"},
+		{Image: "whalebrew/awscli", Description: "Universal command line interface for AWS"},
+		{Image: "whalebrew/ctl"},
+		{Image: "whalebrew/jq", Description: "Command-line JSON processor"},
+		{Image: "whalebrew/cli"},
+	}
+	assert.Equal(t, []string{"whalebrew/cli", "whalebrew/awscli"}, images(Match(results, "cli")))
+	assert.Equal(t, []string{"whalebrew/ctl", "whalebrew/kubectl"}, images(Match(results, "ctl")))
+	assert.Equal(t, []string{"whalebrew/kubectl"}, images(Match(results, "kbctl")))
+	assert.Equal(t, []string{"whalebrew/jq"}, images(Match(results, "JSON")))
+	assert.Empty(t, Match(results, "wget"))
+}
+
+func TestIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index", IndexFileName)
+	idx, err := LoadIndex(path)
+	require.NoError(t, err)
+	searcher := fakeSearcher{name: "first", results: []Result{{Image: "whalebrew/jq"}, {Image: "whalebrew/missing"}}}
+	_, ok := idx.Source(searcher)
+	assert.False(t, ok)
+
+	errs := []error{}
+	source, err := idx.Refresh(context.Background(), searcher, fakeInspecter{
+		"whalebrew/jq": {Config: imagev1.ImageConfig{Entrypoint: []string{"jq"}, Labels: map[string]string{
+			"io.whalebrew.name": "jq",
+			"maintainer":        "someone",
+		}}},
+	}, func(err error) {
+		errs = append(errs, err)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "first", source.Searcher)
+	assert.Equal(t, []Result{
+		{Image: "whalebrew/jq", Entrypoint: []string{"jq"}, Labels: map[string]string{"io.whalebrew.name": "jq"}},
+		{Image: "whalebrew/missing"},
+	}, source.Results)
+	assert.Len(t, errs, 1)
+
+	_, err = idx.Refresh(context.Background(), fakeSearcher{name: "failing", err: errors.New("unreachable")}, nil, nil)
+	assert.EqualError(t, err, "unreachable")
+
+	require.NoError(t, idx.Save())
+	loaded, err := LoadIndex(path)
+	require.NoError(t, err)
+	loadedSource, ok := loaded.Source(searcher)
+	require.True(t, ok)
+	assert.Equal(t, source.Results, loadedSource.Results)
+	assert.True(t, source.UpdatedAt.Equal(loadedSource.UpdatedAt))
+	assert.Len(t, loaded.Sources, 1)
+}
+
+// cancellingInspecter cancels the refresh when the first image is inspected
+type cancellingInspecter struct {
+	fakeInspecter
+	cancel context.CancelFunc
+}
+
+func (c cancellingInspecter) ImageInspectContext(ctx context.Context, image string) (*imagev1.Image, error) {
+	c.cancel()
+	return nil, ctx.Err()
+}
+
+func TestIndexRefreshTimeout(t *testing.T) {
+	idx, err := LoadIndex(filepath.Join(t.TempDir(), IndexFileName))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := []error{}
+	searcher := fakeSearcher{name: "slow", results: []Result{{Image: "whalebrew/jq"}, {Image: "whalebrew/awscli"}}}
+	source, err := idx.Refresh(ctx, searcher, cancellingInspecter{cancel: cancel}, func(err error) {
+		errs = append(errs, err)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Image: "whalebrew/jq"}, {Image: "whalebrew/awscli"}}, source.Results)
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+}
+
+func TestCached(t *testing.T) {
+	search := func(c *Cached, term string) ([]string, []error) {
+		errs := []error{}
+		results := []Result{}
+		for result := range c.Search(context.Background(), term, func(err error) {
+			errs = append(errs, err)
+		}) {
+			results = append(results, result)
+		}
+		return images(results), errs
+	}
+	live := fakeSearcher{name: "live", results: []Result{{Image: "whalebrew/live"}}}
+	failing := fakeSearcher{name: "failing", err: errors.New("unreachable")}
+	idx := &Index{Sources: []Source{
+		{Searcher: "live", UpdatedAt: time.Now(), Results: []Result{{Image: "whalebrew/jq"}, {Image: "whalebrew/wget"}}},
+		{Searcher: "failing", UpdatedAt: time.Now().Add(-48 * time.Hour), Results: []Result{{Image: "whalebrew/jq"}}},
+	}}
+
+	t.Run("fresh index", func(t *testing.T) {
+		found, errs := search(&Cached{Searcher: live, Index: idx, MaxAge: time.Hour}, "jq")
+		assert.Equal(t, []string{"whalebrew/jq"}, found)
+		assert.Empty(t, errs)
+	})
+	t.Run("stale index", func(t *testing.T) {
+		found, errs := search(&Cached{Searcher: live, Index: idx, MaxAge: time.Nanosecond}, "jq")
+		assert.Equal(t, []string{"whalebrew/live"}, found)
+		assert.Empty(t, errs)
+	})
+	t.Run("stale index of a failing searcher", func(t *testing.T) {
+		found, errs := search(&Cached{Searcher: failing, Index: idx, MaxAge: time.Hour}, "jq")
+		assert.Equal(t, []string{"whalebrew/jq"}, found)
+		assert.Len(t, errs, 1)
+	})
+	t.Run("offline", func(t *testing.T) {
+		found, errs := search(&Cached{Searcher: failing, Index: idx, Offline: true}, "jq")
+		assert.Equal(t, []string{"whalebrew/jq"}, found)
+		assert.Empty(t, errs)
+
+		found, errs = search(&Cached{Searcher: fakeSearcher{name: "unknown"}, Index: idx, Offline: true}, "jq")
+		assert.Empty(t, found)
+		assert.Len(t, errs, 1)
+	})
+}
@@ -0,0 +1,129 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/whalebrew/whalebrew/packages"
+	"github.com/whalebrew/whalebrew/run"
+)
+
+const inspectConcurrency = 8
+
+var errNotIndexed = errors.New("the configuration of the image is not indexed")
+
+// contextInspecter inspects images until a context is done, like the inspecter reading images from their registry
+type contextInspecter interface {
+	ImageInspectContext(ctx context.Context, imageName string) (*imagev1.Image, error)
+}
+
+// inspectContext inspects an image, giving up when ctx is done
+func inspectContext(ctx context.Context, inspecter run.ImageInspecter, image string) (*imagev1.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if inspecter, ok := inspecter.(contextInspecter); ok {
+		return inspecter.ImageInspectContext(ctx, image)
+	}
+	return inspecter.ImageInspect(image)
+}
+
+// inspect inspects the image of each result concurrently, until ctx is done.
+// keep updates a result from the configuration of its image, or the inspection error, and tells whether to keep it.
+// keep is never called concurrently.
+func inspect(ctx context.Context, inspecter run.ImageInspecter, results []Result, keep func(*Result, *imagev1.Image, error) bool) []Result {
+	kept := make([]*Result, len(results))
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	slots := make(chan struct{}, inspectConcurrency)
+	for i, result := range results {
+		wg.Add(1)
+		go func(i int, result Result) {
+			defer wg.Done()
+			slots <- struct{}{}
+			imageInspect, err := inspectContext(ctx, inspecter, result.Image)
+			<-slots
+			mutex.Lock()
+			defer mutex.Unlock()
+			if keep(&result, imageInspect, err) {
+				kept[i] = &result
+			}
+		}(i, result)
+	}
+	wg.Wait()
+
+	found := []Result{}
+	for _, result := range kept {
+		if result != nil {
+			found = append(found, *result)
+		}
+	}
+	return found
+}
+
+// indexedInspecter inspects images from the configuration indexed in the results, and the others with Inspecter
+type indexedInspecter struct {
+	Inspecter run.ImageInspecter
+	Indexed   map[string]*imagev1.Image
+}
+
+func (i indexedInspecter) ImageInspect(image string) (*imagev1.Image, error) {
+	if imageInspect, ok := i.Indexed[image]; ok {
+		return imageInspect, nil
+	}
+	if i.Inspecter == nil {
+		return nil, errNotIndexed
+	}
+	return i.Inspecter.ImageInspect(image)
+}
+
+// Packages keeps the results that are whalebrew packages, reading the configuration of their image from the index,
+// or with inspecter when it is not indexed.
+// Images with strict lint errors are dropped, the others are returned with the permissions their package requests.
+// Images that can not be inspected are reported to handleError and dropped.
+// When inspecter is nil, the images whose configuration is not indexed are dropped.
+func Packages(inspecter run.ImageInspecter, results []Result, handleError ErrorHandler) []Result {
+	indexed := map[string]*imagev1.Image{}
+	for _, result := range results {
+		if result.Entrypoint != nil || result.Labels != nil {
+			indexed[result.Image] = &imagev1.Image{Config: imagev1.ImageConfig{Entrypoint: result.Entrypoint, Labels: result.Labels}}
+		}
+	}
+	return inspect(context.Background(), indexedInspecter{Inspecter: inspecter, Indexed: indexed}, results, func(result *Result, imageInspect *imagev1.Image, err error) bool {
+		if err == errNotIndexed {
+			return false
+		}
+		if err != nil {
+			handleError(fmt.Errorf("%s: %w", result.Image, err))
+			return false
+		}
+		pkg, err := packageOf(result.Image, imageInspect)
+		if err != nil {
+			handleError(fmt.Errorf("%s: %w", result.Image, err))
+			return false
+		}
+		if pkg == nil {
+			return false
+		}
+		permissions := pkg.Permissions()
+		result.Permissions = &permissions
+		return true
+	})
+}
+
+// packageOf returns the package installing image would create, or nil when image is not a valid package
+func packageOf(image string, imageInspect *imagev1.Image) (*packages.Package, error) {
+	valid := true
+	packages.LintImage(imageInspect, func(err error) {
+		if strictError, ok := err.(packages.StrictError); !ok || strictError.Strict() {
+			valid = false
+		}
+	})
+	if !valid {
+		return nil, nil
+	}
+	return packages.NewPackageFromImage(image, imageInspect)
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"errors"
+	"testing"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/whalebrew/whalebrew/packages"
+)
+
+type fakeInspecter map[string]*imagev1.Image
+
+func (f fakeInspecter) ImageInspect(image string) (*imagev1.Image, error) {
+	if i, ok := f[image]; ok {
+		return i, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestPackages(t *testing.T) {
+	inspecter := fakeInspecter{
+		"whalebrew/awscli": {Config: imagev1.ImageConfig{
+			Entrypoint: []string{"aws"},
+			Labels: map[string]string{
+				"io.whalebrew.config.volumes":     `["~/.aws:/root/.aws"]`,
+				"io.whalebrew.config.environment": `["AWS_PROFILE"]`,
+				"io.whalebrew.unknown":            "value",
+			},
+		}},
+		"whalebrew/server": {Config: imagev1.ImageConfig{
+			Entrypoint: []string{"serve"},
+			Labels:     map[string]string{"io.whalebrew.config.ports": `["8080:80"]`},
+		}},
+		"whalebrew/base":    {},
+		"whalebrew/invalid": {Config: imagev1.ImageConfig{Entrypoint: []string{"invalid"}, Labels: map[string]string{"io.whalebrew.config.ports": "["}}},
+	}
+	errs := []error{}
+	results := Packages(inspecter, []Result{
+		{Image: "whalebrew/awscli", Stars: 2},
+		{Image: "whalebrew/base"},
+		{Image: "whalebrew/missing"},
+		{Image: "whalebrew/invalid"},
+		{Image: "whalebrew/server"},
+	}, func(err error) {
+		errs = append(errs, err)
+	})
+	assert.Equal(t, []Result{
+		{Image: "whalebrew/awscli", Stars: 2, Permissions: &packages.Permissions{Volumes: []string{"~/.aws:/root/.aws"}, Environment: []string{"AWS_PROFILE"}}},
+		{Image: "whalebrew/server", Permissions: &packages.Permissions{Ports: []string{"8080:80"}}},
+	}, results)
+	if assert.Len(t, errs, 1) {
+		assert.EqualError(t, errs[0], "whalebrew/missing: not found")
+	}
+}
+
+func TestPackagesIndexed(t *testing.T) {
+	indexed := []Result{
+		{Image: "whalebrew/jq", Entrypoint: []string{"jq"}},
+		{Image: "whalebrew/server", Entrypoint: []string{"serve"}, Labels: map[string]string{"io.whalebrew.config.ports": `["8080:80"]`}},
+		{Image: "whalebrew/base"},
+	}
+	errs := []error{}
+	results := Packages(nil, indexed, func(err error) {
+		errs = append(errs, err)
+	})
+	assert.Equal(t, []Result{
+		{Image: "whalebrew/jq", Entrypoint: []string{"jq"}, Permissions: &packages.Permissions{}},
+		{Image: "whalebrew/server", Entrypoint: []string{"serve"}, Labels: map[string]string{"io.whalebrew.config.ports": `["8080:80"]`}, Permissions: &packages.Permissions{Ports: []string{"8080:80"}}},
+	}, results, "images whose configuration is not indexed are dropped without an inspecter")
+	assert.Empty(t, errs)
+
+	results = Packages(fakeInspecter{"whalebrew/base": {Config: imagev1.ImageConfig{Entrypoint: []string{"sh"}}}}, indexed, func(err error) {
+		errs = append(errs, err)
+	})
+	assert.Len(t, results, 3, "images whose configuration is not indexed are inspected")
+	assert.Empty(t, errs)
+}
@@ -1,21 +1,87 @@
 package search
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/whalebrew/whalebrew/config"
 	"github.com/whalebrew/whalebrew/dockerregistry"
+	"github.com/whalebrew/whalebrew/packages"
 )
 
-// ErrorHandler handles the logic when an error occurs and returns whether to continue or stop
-type ErrorHandler func(error) (abort bool)
+// ErrorHandler reports the errors occurring while searching.
+// A searcher stops on its first error, the other searchers are not affected.
+type ErrorHandler func(error)
 
-// Searcher searches a registry for images matching a term
+// Result is an image found in a registry.
+// Registries not providing the details of images only provide the image name.
+type Result struct {
+	Image       string     `json:"image" yaml:"image"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	LastUpdated *time.Time `json:"last_updated,omitempty" yaml:"last_updated,omitempty"`
+	Stars       int        `json:"stars,omitempty" yaml:"stars,omitempty"`
+	Pulls       int64      `json:"pulls,omitempty" yaml:"pulls,omitempty"`
+	Tags        []Tag      `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Entrypoint and Labels are the entrypoint and the whalebrew labels of the image, only known for indexed images
+	Entrypoint []string          `json:"entrypoint,omitempty" yaml:"entrypoint,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Permissions are only known when searching for packages
+	Permissions *packages.Permissions `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+}
+
+// Tag is a tag of an image
+type Tag struct {
+	Name        string     `json:"name" yaml:"name"`
+	LastUpdated *time.Time `json:"last_updated,omitempty" yaml:"last_updated,omitempty"`
+	Digest      string     `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
+// Searcher searches a registry for images matching a term and lists their tags
 type Searcher interface {
-	Search(term string, errorHandler ErrorHandler) <-chan string
+	Search(ctx context.Context, term string, errorHandler ErrorHandler) <-chan Result
+	Tags(ctx context.Context, image string) ([]Tag, error)
 }
 
-// ForRegistries initialises searchers from whalebrew configuration
+// Search searches all the searchers concurrently, until ctx is done.
+// Results are de-duplicated and ordered like the searchers.
+// Errors are reported to handleError, prefixed with the searcher, without stopping the other searches.
+func Search(ctx context.Context, searchers []Searcher, term string, handleError ErrorHandler) []Result {
+	found := make([][]Result, len(searchers))
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i, searcher := range searchers {
+		wg.Add(1)
+		go func(i int, searcher Searcher) {
+			defer wg.Done()
+			results := searcher.Search(ctx, term, func(err error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				handleError(fmt.Errorf("%v: %w", searcher, err))
+			})
+			for result := range results {
+				found[i] = append(found[i], result)
+			}
+		}(i, searcher)
+	}
+	wg.Wait()
+
+	merged := []Result{}
+	seen := map[string]bool{}
+	for _, results := range found {
+		for _, result := range results {
+			if !seen[result.Image] {
+				seen[result.Image] = true
+				merged = append(merged, result)
+			}
+		}
+	}
+	return merged
+}
+
+// ForRegistries initialises searchers from whalebrew configuration.
+// Unsupported registries are reported to handleError and skipped.
 func ForRegistries(registries []config.Registry, handleError ErrorHandler) <-chan Searcher {
 	if len(registries) == 0 {
 		registries = []config.Registry{
@@ -45,10 +111,7 @@ func ForRegistries(registries []config.Registry, handleError ErrorHandler) <-cha
 				}
 				continue
 			} else {
-				if handleError(fmt.Errorf("unsupported configuration at index %d: %v", idx, registry)) {
-					close(out)
-					return
-				}
+				handleError(fmt.Errorf("unsupported configuration at index %d: %v", idx, registry))
 			}
 		}
 		close(out)
@@ -56,7 +119,6 @@ func ForRegistries(registries []config.Registry, handleError ErrorHandler) <-cha
 	return out
 }
 
-func defaultErrorHandler(err error) (abort bool) {
+func defaultErrorHandler(err error) {
 	fmt.Println(err.Error())
-	return true
 }
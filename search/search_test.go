@@ -1,6 +1,8 @@
 package search
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -8,16 +10,14 @@ import (
 )
 
 func TestForRepositories(t *testing.T) {
-	for searcher := range ForRegistries([]config.Registry{{}}, func(err error) bool {
+	for searcher := range ForRegistries([]config.Registry{{}}, func(err error) {
 		assert.Error(t, err)
-		return true
 	}) {
 		t.Errorf("no searcher should be returned for empty config, got: %v", searcher)
 	}
 	count := 0
-	for searcher := range ForRegistries([]config.Registry{}, func(err error) bool {
+	for searcher := range ForRegistries([]config.Registry{}, func(err error) {
 		t.Errorf("With a valid config, no error should be raised. Got: %v", err)
-		return true
 	}) {
 		assert.IsType(t, &DockerHub{}, searcher)
 		if dh, ok := searcher.(*DockerHub); ok {
@@ -26,4 +26,57 @@ func TestForRepositories(t *testing.T) {
 		count++
 	}
 	assert.Equal(t, 1, count)
+
+	errs := []error{}
+	searchers := []Searcher{}
+	for searcher := range ForRegistries([]config.Registry{{}, {DockerHub: &config.DockerHubRegistry{Owner: "bitnami"}}}, func(err error) {
+		errs = append(errs, err)
+	}) {
+		searchers = append(searchers, searcher)
+	}
+	assert.Len(t, errs, 1)
+	assert.Equal(t, []Searcher{&DockerHub{Owner: "bitnami"}}, searchers, "unsupported registries are skipped")
+}
+
+type fakeSearcher struct {
+	name    string
+	results []Result
+	err     error
+}
+
+func (f fakeSearcher) String() string {
+	return f.name
+}
+
+func (f fakeSearcher) Search(ctx context.Context, term string, handleError ErrorHandler) <-chan Result {
+	out := make(chan Result)
+	go func() {
+		defer close(out)
+		for _, result := range f.results {
+			out <- result
+		}
+		if f.err != nil {
+			handleError(f.err)
+		}
+	}()
+	return out
+}
+
+func (f fakeSearcher) Tags(ctx context.Context, image string) ([]Tag, error) {
+	return nil, nil
+}
+
+func TestSearch(t *testing.T) {
+	errs := []error{}
+	results := Search(context.Background(), []Searcher{
+		fakeSearcher{name: "first", results: []Result{{Image: "whalebrew/jq"}, {Image: "whalebrew/wget"}}},
+		fakeSearcher{name: "failing", err: errors.New("unreachable")},
+		fakeSearcher{name: "second", results: []Result{{Image: "whalebrew/wget", Stars: 3}, {Image: "my-registry/jq"}}},
+	}, "term", func(err error) {
+		errs = append(errs, err)
+	})
+	assert.Equal(t, []Result{{Image: "whalebrew/jq"}, {Image: "whalebrew/wget"}, {Image: "my-registry/jq"}}, results)
+	if assert.Len(t, errs, 1) {
+		assert.EqualError(t, errs[0], "failing: unreachable")
+	}
 }
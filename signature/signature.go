@@ -0,0 +1,177 @@
+package signature
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+)
+
+const (
+	// MediaTypeSimpleSigning is the media type of the layers holding the payloads of cosign signatures
+	MediaTypeSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// AnnotationSignature is the layer annotation holding the base64 encoded signature of the payload
+	AnnotationSignature = "dev.cosignproject.cosign/signature"
+	// PayloadType is the type of the payloads signing container images
+	PayloadType = "cosign container image signature"
+)
+
+// Payload is the simple signing document signed for an image
+type Payload struct {
+	Critical Critical               `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+// Critical is the part of the payload identifying the signed image
+type Critical struct {
+	Identity Identity `json:"identity"`
+	Image    Image    `json:"image"`
+	Type     string   `json:"type"`
+}
+
+// Identity is the repository the image was signed for
+type Identity struct {
+	DockerReference string `json:"docker-reference"`
+}
+
+// Image is the manifest digest of the signed image
+type Image struct {
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+// Tag returns the tag the signatures of the image with the given digest are stored with, next to the image
+func Tag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".sig"
+}
+
+// LoadPublicKey reads a PEM encoded ECDSA public key, as generated by cosign generate-key-pair
+func LoadPublicKey(path string) (*ecdsa.PublicKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s is not a PEM encoded public key", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an ECDSA key", path)
+	}
+	return ecdsaKey, nil
+}
+
+// RuleFor returns the verification rule applying to the image, the one of its owner having precedence on the one of its registry.
+// It returns nil when the image does not need to be verified.
+func RuleFor(rules []config.Verify, ref dockerregistry.Reference) *config.Verify {
+	var found *config.Verify
+	for i, rule := range rules {
+		registry := strings.TrimSuffix(rule.Registry, "/")
+		if ref.Domain != registry && !strings.HasPrefix(ref.Name(), registry+"/") {
+			continue
+		}
+		if found == nil || len(registry) > len(strings.TrimSuffix(found.Registry, "/")) {
+			found = &rules[i]
+		}
+	}
+	return found
+}
+
+// Keys loads the public keys of a rule. Relative paths are relative to dir.
+func Keys(rule *config.Verify, dir string) ([]*ecdsa.PublicKey, error) {
+	if len(rule.Keys) == 0 {
+		return nil, fmt.Errorf("no keys are configured to verify images of %s", rule.Registry)
+	}
+	keys := []*ecdsa.PublicKey{}
+	for _, path := range rule.Keys {
+		path = os.ExpandEnv(path)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		key, err := LoadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Verifier verifies the signatures stored in a registry next to the images
+type Verifier struct {
+	Registry *dockerregistry.Registry
+	Keys     []*ecdsa.PublicKey
+}
+
+// Verify checks the image is signed with one of the keys and returns the digest of its signed manifest.
+// When the reference has no digest, the manifest its tag points to is verified.
+func (v *Verifier) Verify(ref dockerregistry.Reference) (string, error) {
+	digest := ref.Digest
+	if digest == "" {
+		var err error
+		digest, err = v.Registry.ManifestDigest(ref.Path, ref.Tag)
+		if err != nil {
+			return "", fmt.Errorf("unable to resolve the digest of %s: %w", ref, err)
+		}
+	}
+	m, err := v.Registry.Manifest(context.Background(), ref.Path, Tag(digest))
+	if err != nil {
+		return "", fmt.Errorf("no signature found for %s@%s: %w", ref.Name(), digest, err)
+	}
+	manifest := imagev1.Manifest{}
+	if err := json.Unmarshal(m.Content, &manifest); err != nil {
+		return "", fmt.Errorf("invalid signature manifest of %s@%s: %w", ref.Name(), digest, err)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != MediaTypeSimpleSigning {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(layer.Annotations[AnnotationSignature])
+		if err != nil || len(signature) == 0 {
+			continue
+		}
+		content, err := v.Registry.Blob(context.Background(), ref.Path, layer.Digest.String())
+		if err != nil {
+			return "", fmt.Errorf("unable to download the signature payload of %s@%s: %w", ref.Name(), digest, err)
+		}
+		if !v.signed(content, signature) {
+			continue
+		}
+		payload := Payload{}
+		if err := json.Unmarshal(content, &payload); err != nil {
+			return "", fmt.Errorf("invalid signature payload of %s@%s: %w", ref.Name(), digest, err)
+		}
+		if payload.Critical.Type != PayloadType || payload.Critical.Image.DockerManifestDigest != digest {
+			// a valid signature of another image, copied next to this one
+			continue
+		}
+		return digest, nil
+	}
+	return "", fmt.Errorf("%s@%s is not signed with any of the configured keys", ref.Name(), digest)
+}
+
+// signed reports whether one of the keys signed content
+func (v *Verifier) signed(content, signature []byte) bool {
+	hash := sha256.Sum256(content)
+	for _, key := range v.Keys {
+		if ecdsa.VerifyASN1(key, hash[:], signature) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package signature
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalebrew/whalebrew/config"
+	"github.com/whalebrew/whalebrew/dockerregistry"
+)
+
+const imageManifest = `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`
+
+// testRegistry serves an image and the signatures stored next to it
+type testRegistry struct {
+	manifests map[string]string
+	blobs     map[string]string
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if content, ok := r.manifests[strings.TrimPrefix(req.URL.Path, "/v2/whalebrew/jq/manifests/")]; ok {
+		w.Header().Set("Docker-Content-Digest", digestOf(content))
+		fmt.Fprint(w, content)
+		return
+	}
+	if content, ok := r.blobs[strings.TrimPrefix(req.URL.Path, "/v2/whalebrew/jq/blobs/")]; ok {
+		fmt.Fprint(w, content)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func digestOf(content string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(content)))
+}
+
+// sign stores in r a signature of digest made with key
+func (r *testRegistry) sign(t *testing.T, key *ecdsa.PrivateKey, digest, signedDigest string) {
+	t.Helper()
+	payload, err := json.Marshal(Payload{Critical: Critical{
+		Identity: Identity{DockerReference: "index.docker.io/whalebrew/jq"},
+		Image:    Image{DockerManifestDigest: signedDigest},
+		Type:     PayloadType,
+	}})
+	require.NoError(t, err)
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers": []map[string]interface{}{{
+			"mediaType":   MediaTypeSimpleSigning,
+			"digest":      digestOf(string(payload)),
+			"size":        len(payload),
+			"annotations": map[string]string{AnnotationSignature: base64.StdEncoding.EncodeToString(signature)},
+		}},
+	})
+	require.NoError(t, err)
+	r.manifests[Tag(digest)] = string(manifest)
+	r.blobs[digestOf(string(payload))] = string(payload)
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func writePublicKey(t *testing.T, dir string, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "cosign.pub")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+	return path
+}
+
+func TestTag(t *testing.T) {
+	assert.Equal(t, "sha256-1234.sig", Tag("sha256:1234"))
+}
+
+func TestVerify(t *testing.T) {
+	key, other := newKey(t), newKey(t)
+	registry := &testRegistry{manifests: map[string]string{"latest": imageManifest}, blobs: map[string]string{}}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	v := &Verifier{
+		Registry: &dockerregistry.Registry{Host: strings.TrimPrefix(server.URL, "http://"), UseHTTP: true},
+		Keys:     []*ecdsa.PublicKey{&key.PublicKey},
+	}
+	ref, err := dockerregistry.ParseReference("whalebrew/jq")
+	require.NoError(t, err)
+	digest := digestOf(imageManifest)
+
+	_, err = v.Verify(ref)
+	assert.Error(t, err, "unsigned images must be refused")
+
+	registry.sign(t, other, digest, digest)
+	_, err = v.Verify(ref)
+	assert.Error(t, err, "images signed with other keys must be refused")
+
+	registry.sign(t, key, digest, "sha256:5678")
+	_, err = v.Verify(ref)
+	assert.Error(t, err, "signatures of other images must be refused")
+
+	registry.sign(t, key, digest, digest)
+	verified, err := v.Verify(ref)
+	require.NoError(t, err)
+	assert.Equal(t, digest, verified)
+
+	ref.Tag, ref.Digest = "", digest
+	verified, err = v.Verify(ref)
+	require.NoError(t, err)
+	assert.Equal(t, digest, verified)
+
+	ref.Digest = "sha256:5678"
+	_, err = v.Verify(ref)
+	assert.Error(t, err)
+}
+
+func TestRuleFor(t *testing.T) {
+	rules := []config.Verify{
+		{Registry: "docker.io"},
+		{Registry: "docker.io/whalebrew/"},
+		{Registry: "ghcr.io/my-org"},
+	}
+	for image, expected := range map[string]*config.Verify{
+		"whalebrew/jq":                      &rules[1],
+		"other/jq":                          &rules[0],
+		"index.docker.io/other/jq":          &rules[0],
+		"registry-1.docker.io/whalebrew/jq": &rules[1],
+		"ghcr.io/my-org/tool":               &rules[2],
+		"ghcr.io/my-organisation/jq":        nil,
+		"registry.example.com/org/jq":       nil,
+	} {
+		ref, err := dockerregistry.ParseReference(image)
+		require.NoError(t, err)
+		assert.Equal(t, expected, RuleFor(rules, ref), image)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	dir := t.TempDir()
+	writePublicKey(t, dir, newKey(t))
+	keys, err := Keys(&config.Verify{Keys: []string{"cosign.pub"}}, dir)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	_, err = Keys(&config.Verify{}, dir)
+	assert.Error(t, err)
+	_, err = Keys(&config.Verify{Keys: []string{"missing.pub"}}, dir)
+	assert.Error(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.pub"), []byte("not a key"), 0644))
+	_, err = Keys(&config.Verify{Keys: []string{filepath.Join(dir, "invalid.pub")}}, "/")
+	assert.Error(t, err)
+}